@@ -0,0 +1,640 @@
+package riffkey
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Outcome describes what happened to one config entry when bindings were
+// loaded.
+type Outcome uint8
+
+const (
+	OutcomeApplied        Outcome = iota // the entry took effect
+	OutcomeUnknownAction                 // no binding has that name; ignored
+	OutcomeInvalidValue                  // the value isn't a string; ignored
+	OutcomeInvalidPattern                // the pattern has unknown keys; applied as literals
+	OutcomeOverridden                    // replaced by a later entry, e.g. in the app section
+	OutcomeConflict                      // applied, but another binding has the same keys
+	OutcomeMissingSection                // the app has no section in the file
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeApplied:
+		return "applied"
+	case OutcomeUnknownAction:
+		return "unknown action"
+	case OutcomeInvalidValue:
+		return "invalid value"
+	case OutcomeInvalidPattern:
+		return "invalid pattern"
+	case OutcomeOverridden:
+		return "overridden"
+	case OutcomeConflict:
+		return "conflicting"
+	case OutcomeMissingSection:
+		return "missing section"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic reports the outcome of one config entry.
+type Diagnostic struct {
+	File    string
+	Line    int // 1-based; 0 if the entry has no position (a missing section)
+	Column  int
+	Section string // "aliases", "global" or the app name; empty for an include
+	Name    string // binding or alias name; empty for a missing section
+	Value   string // the entry's value as written
+	Outcome Outcome
+	Detail  string // e.g. the conflicting binding or the unknown key
+}
+
+// String formats the diagnostic as "file:line:col: [section] name: outcome (detail)".
+func (d Diagnostic) String() string {
+	var parts []string
+	if d.File != "" {
+		loc := d.File
+		if d.Line > 0 {
+			loc += fmt.Sprintf(":%d:%d", d.Line, d.Column)
+		}
+		parts = append(parts, loc+":")
+	}
+	if d.Section != "" {
+		parts = append(parts, "["+d.Section+"]")
+	}
+	if d.Name != "" {
+		parts = append(parts, d.Name)
+	}
+	s := strings.Join(parts, " ") + ": " + d.Outcome.String()
+	if d.Detail != "" {
+		s += " (" + d.Detail + ")"
+	}
+	return s
+}
+
+// configFile is a parsed config file.
+type configFile struct {
+	path     string
+	data     string
+	raw      map[string]any
+	includes []string // include entries whose files don't exist
+}
+
+// readConfigs reads and parses the files at paths, lowest precedence first,
+// following include = [...] entries. A file's includes come before it in the
+// result, so its own entries win. Missing files are skipped; a file reached
+// twice is only read the first time.
+func readConfigs(paths []string) ([]configFile, error) {
+	var files []configFile
+	seen := make(map[string]bool)
+
+	var read func(path string) (bool, error)
+	read = func(path string) (bool, error) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if seen[path] {
+			return true, nil
+		}
+		seen[path] = true
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil // Missing config is fine
+			}
+			return false, err
+		}
+		var raw map[string]any
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return false, fmt.Errorf("%s: %w", path, err)
+		}
+
+		f := configFile{path: path, data: string(data), raw: raw}
+		var includes []any
+		switch v := raw["include"].(type) {
+		case string:
+			includes = []any{v}
+		case []any:
+			includes = v
+		}
+		for _, inc := range includes {
+			name, ok := inc.(string)
+			if !ok {
+				continue
+			}
+			if rest, ok := strings.CutPrefix(name, "~/"); ok {
+				if home, err := os.UserHomeDir(); err == nil {
+					name = filepath.Join(home, rest)
+				}
+			}
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(filepath.Dir(path), name)
+			}
+			found, err := read(name)
+			if err != nil {
+				return false, err
+			}
+			if !found {
+				f.includes = append(f.includes, name)
+			}
+		}
+		files = append(files, f)
+		return true, nil
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := read(path); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// applyConfigs applies parsed config files to the router and the mode
+// routers in order and returns a Diagnostic for every entry.
+func (r *Router) applyConfigs(files []configFile, appName string, modes []*Router) []Diagnostic {
+	l := &configLoad{latest: make(map[latestKey]int), modes: make(map[string]*Router)}
+	routers := []*Router{r}
+	for _, m := range append([]*Router{r}, modes...) {
+		if m.name == "" {
+			continue
+		}
+		if _, ok := l.modes[m.name]; !ok {
+			l.modes[m.name] = m
+			if m != r {
+				routers = append(routers, m)
+			}
+		}
+	}
+
+	order := make(map[string]int, len(files))
+	foundApp := false
+	for i, f := range files {
+		order[f.path] = i
+		l.file = f.path
+		l.pos = keyPositions(f.data)
+
+		// A top-level notation key selects the syntax of the file's
+		// patterns. Patterns in another notation than the router's are
+		// converted.
+		l.from, l.convertFrom = NotationVim, false
+		if name, ok := f.raw["notation"].(string); ok {
+			l.from, l.convertFrom = ParseNotation(name)
+		}
+
+		l.r = r
+		for _, inc := range f.includes {
+			l.report("", "include", "include", inc, OutcomeInvalidValue, "file not found")
+		}
+
+		// Apply global aliases to every router
+		if aliases, ok := f.raw["aliases"].(map[string]any); ok {
+			l.applyAliases(aliases, routers)
+		}
+
+		// Apply global bindings, then cross-app mode defaults from
+		// [global.<mode>]
+		if global, ok := f.raw["global"].(map[string]any); ok {
+			l.r = r
+			l.applySection("global", "", global)
+			for _, m := range routers {
+				if section, ok := global[m.name].(map[string]any); ok && m.name != "" {
+					l.r = m
+					l.applySection("global."+m.name, "", section)
+				}
+			}
+		}
+
+		// Apply app-specific bindings (override global), then [app.<mode>]
+		// Supports dotted names like "browse.toc" which map to [browse.toc] in TOML
+		if appSection := getNestedSection(f.raw, appName); appSection != nil {
+			foundApp = true
+			l.r = r
+			l.applySection(appName, "", appSection)
+			for _, m := range routers {
+				if section, ok := appSection[m.name].(map[string]any); ok && m.name != "" {
+					l.r = m
+					l.applySection(appName+"."+m.name, "", section)
+				}
+			}
+		}
+	}
+	if len(files) > 0 && !foundApp {
+		l.file = ""
+		l.r = r
+		l.report(appName, "", "", "", OutcomeMissingSection, "")
+	}
+
+	l.markConflicts()
+	slices.SortStableFunc(l.diags, func(a, b Diagnostic) int {
+		if a.File != b.File {
+			return order[a.File] - order[b.File]
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return l.diags
+}
+
+// configLoad applies config files to a router and its mode routers and
+// records a Diagnostic for every entry.
+type configLoad struct {
+	r           *Router            // router the current section applies to
+	modes       map[string]*Router // mode routers by name; their sections aren't bindings
+	file        string
+	pos         map[string]position
+	from        Notation // notation of the current file's patterns
+	convertFrom bool     // whether the file declared a notation
+	diags       []Diagnostic
+	targets     []*Router         // router each diagnostic's entry applied to
+	latest      map[latestKey]int // entry in effect -> its diagnostic
+}
+
+type latestKey struct {
+	r    *Router
+	name string // "alias <name>" or "binding <name>"
+}
+
+// convert rewrites a pattern from the file's notation to the router's.
+func (l *configLoad) convert(r *Router, p string) string {
+	if !l.convertFrom || l.from == r.notation {
+		return p
+	}
+	return r.notation.Format(l.from.Parse(r.expanded(p)))
+}
+
+type position struct{ line, column int }
+
+func (l *configLoad) report(section, path, name, value string, o Outcome, detail string) {
+	p := l.pos[path]
+	l.diags = append(l.diags, Diagnostic{
+		File:    l.file,
+		Line:    p.line,
+		Column:  p.column,
+		Section: section,
+		Name:    name,
+		Value:   value,
+		Outcome: o,
+		Detail:  detail,
+	})
+	l.targets = append(l.targets, l.r)
+}
+
+// supersede records the last reported diagnostic as the entry in effect for
+// key, marking the one it replaces as overridden.
+func (l *configLoad) supersede(r *Router, name string) {
+	key := latestKey{r, name}
+	cur := len(l.diags) - 1
+	if prev, ok := l.latest[key]; ok {
+		d := &l.diags[prev]
+		d.Outcome = OutcomeOverridden
+		d.Detail = "by [" + l.diags[cur].Section + "]"
+		if d.File != l.file {
+			d.Detail = "by " + l.file + " [" + l.diags[cur].Section + "]"
+		}
+	}
+	l.latest[key] = cur
+}
+
+// applyAliases sets every string entry of the [aliases] section on each of
+// the routers.
+func (l *configLoad) applyAliases(aliases map[string]any, routers []*Router) {
+	for name, expansion := range aliases {
+		s, ok := expansion.(string)
+		if !ok {
+			l.report("aliases", "aliases."+name, name, fmt.Sprint(expansion), OutcomeInvalidValue, "")
+			continue
+		}
+		for _, r := range routers {
+			r.SetAlias(name, l.convert(r, s))
+		}
+		l.report("aliases", "aliases."+name, name, s, OutcomeApplied, "")
+		l.supersede(routers[0], "alias "+strings.ToLower(name))
+	}
+}
+
+// applySection rebinds every string entry in a config section. Nested tables
+// come from dotted keys such as git.status = "..." and address bindings of
+// mounted sub-routers by their namespaced name. Tables named after a mode
+// router are mode sections, applied separately.
+func (l *configLoad) applySection(section, prefix string, entries map[string]any) {
+	for name, value := range entries {
+		full := prefix + name
+		path := section + "." + full
+		switch v := value.(type) {
+		case string:
+			if !l.r.knownBinding(full) {
+				l.report(section, path, full, v, OutcomeUnknownAction, "")
+				continue
+			}
+			if v == "" {
+				l.unbind(section, path, full, v)
+				continue
+			}
+			pattern := l.convert(l.r, v)
+			if !l.r.Rebind(full, pattern) {
+				l.report(section, path, full, v, OutcomeInvalidPattern, "must start with the mount prefix")
+				continue
+			}
+			l.r.setSource(full, l.file)
+			// check what the file wrote, in its own notation: converting
+			// reads unknown key names as literals
+			notation, written := l.r.notation, pattern
+			if l.convertFrom {
+				notation, written = l.from, v
+			}
+			if err := notation.check(l.r.expanded(written)); err != nil {
+				l.report(section, path, full, v, OutcomeInvalidPattern, err.Error())
+			} else {
+				l.report(section, path, full, v, OutcomeApplied, "")
+			}
+			l.supersede(l.r, "binding "+full)
+		case bool:
+			if v || !l.r.knownBinding(full) {
+				outcome := OutcomeInvalidValue
+				if !v {
+					outcome = OutcomeUnknownAction
+				}
+				l.report(section, path, full, fmt.Sprint(v), outcome, "")
+				continue
+			}
+			l.unbind(section, path, full, "false")
+		case map[string]any:
+			if _, ok := l.modes[full]; ok && prefix == "" {
+				continue
+			}
+			l.applySection(section, full+".", v)
+		default:
+			l.report(section, path, full, fmt.Sprint(v), OutcomeInvalidValue, "")
+		}
+	}
+}
+
+// unbind applies an entry that unbinds a binding (name = "" or false).
+func (l *configLoad) unbind(section, path, name, value string) {
+	l.r.Unbind(name)
+	l.r.setSource(name, l.file)
+	l.report(section, path, name, value, OutcomeApplied, "unbound")
+	l.supersede(l.r, "binding "+name)
+}
+
+// markConflicts marks applied entries whose keys collide with another
+// binding of the same router.
+func (l *configLoad) markConflicts() {
+	resolved := make(map[*Router][]resolvedBinding)
+	for i := range l.diags {
+		d := &l.diags[i]
+		if d.Outcome != OutcomeApplied || d.Section == "aliases" || d.Detail == "unbound" {
+			continue
+		}
+		r := l.targets[i]
+		if _, ok := resolved[r]; !ok {
+			resolved[r] = r.resolvedBindings()
+		}
+		bindings := resolved[r]
+		j := slices.IndexFunc(bindings, func(b resolvedBinding) bool { return b.Name == d.Name })
+		if j == -1 {
+			continue
+		}
+		for _, other := range bindings {
+			if other.Name != d.Name && slices.Equal(other.keys, bindings[j].keys) {
+				d.Outcome = OutcomeConflict
+				d.Detail = "same keys as " + other.Name
+				break
+			}
+		}
+	}
+}
+
+// setSource records the config file that set a binding's pattern.
+func (r *Router) setSource(name, source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.namedBindings[name]; ok {
+		b.source = source
+		return
+	}
+	if m, local, ok := r.mountFor(name); ok {
+		m.sub.setSource(local, source)
+	}
+}
+
+// knownBinding reports whether name is a binding Rebind can address: one of
+// the router's own, an inherited one or one in a mounted sub-router.
+func (r *Router) knownBinding(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.namedBindings[name]; ok {
+		return true
+	}
+	if _, ok := r.inheritedBinding(name); ok {
+		return true
+	}
+	if m, local, ok := r.mountFor(name); ok {
+		return m.sub.knownBinding(local)
+	}
+	return false
+}
+
+// check reports patterns the notation's lenient parser would accept only by
+// reading unknown key names as literal characters.
+func (n Notation) check(pattern string) error {
+	if len(n.Parse(pattern)) == 0 {
+		return errors.New("empty pattern")
+	}
+	switch n {
+	case NotationVim:
+		rest := pattern
+		for {
+			start := strings.IndexByte(rest, '<')
+			if start == -1 {
+				return nil
+			}
+			end := strings.IndexByte(rest[start:], '>')
+			if end == -1 {
+				return nil
+			}
+			name := rest[start+1 : start+end]
+			if looksLikeKeyName(name) {
+				if _, ok := parseVimKey(name); !ok {
+					return fmt.Errorf("unknown key <%s>", name)
+				}
+			}
+			rest = rest[start+1:]
+		}
+	case NotationEmacs:
+		for _, chord := range strings.Fields(pattern) {
+			for len(chord) > 2 && chord[1] == '-' && strings.IndexByte("CMASs", chord[0]) != -1 {
+				chord = chord[2:]
+			}
+			if len(chord) > 2 && chord[0] == '<' && chord[len(chord)-1] == '>' {
+				if _, ok := emacsToSpecial[strings.ToLower(chord)]; !ok {
+					return fmt.Errorf("unknown key %s", chord)
+				}
+			}
+		}
+	case NotationVSCode:
+		for _, chord := range strings.Fields(pattern) {
+			if chord == "+" {
+				continue
+			}
+			mods := strings.Split(strings.TrimSuffix(chord, "+"), "+")
+			for _, m := range mods[:len(mods)-1] {
+				switch strings.ToLower(m) {
+				case "ctrl", "control", "alt", "option", "opt", "shift", "meta", "cmd", "win", "super":
+				default:
+					return fmt.Errorf("unknown modifier %q", m)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// looksLikeKeyName reports whether s could be meant as a <...> key name
+// rather than literal text: two or more letters, digits or dashes starting
+// with a letter.
+func looksLikeKeyName(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// keyPositions maps each key defined in a TOML document, written as a dotted
+// path including its table, to the line and column where it is defined.
+func keyPositions(doc string) map[string]position {
+	pos := make(map[string]position)
+	for i, l := range tomlLines(doc) {
+		if l.key != nil {
+			path := strings.Join(append(slices.Clone(l.table), l.key...), ".")
+			pos[path] = position{line: i + 1, column: len([]rune(l.text[:l.indent])) + 1}
+		}
+	}
+	return pos
+}
+
+// tomlLine is one line of a TOML document as seen by tomlLines.
+type tomlLine struct {
+	text   string
+	table  []string // table the line belongs to; for a header, the table it opens
+	header bool     // the line is a [table] or [[array]] header
+	key    []string // for a key = value line, the dotted key
+	indent int      // byte offset of the key
+	value  int      // byte offset of the value, after '='
+}
+
+// tomlLines splits a TOML document into lines, recognising table headers
+// and key = value lines. It understands dotted and quoted keys and skips
+// the bodies of multi-line strings, which covers riffkey config files; other
+// lines (comments, blanks, continuations) have neither header nor key set.
+func tomlLines(doc string) []tomlLine {
+	var lines []tomlLine
+	var table []string
+	closing := "" // delimiter of the multi-line string being skipped
+	for _, text := range strings.Split(doc, "\n") {
+		l := tomlLine{text: text, table: table}
+		lines = append(lines, l)
+		if closing != "" {
+			if strings.Contains(text, closing) {
+				closing = ""
+			}
+			continue
+		}
+		trimmed := strings.TrimLeft(text, " \t")
+		indent := len(text) - len(trimmed)
+		switch {
+		case trimmed == "" || trimmed[0] == '#':
+		case trimmed[0] == '[':
+			table, _ = parseKeyPath(strings.TrimLeft(trimmed, "["))
+			l.table, l.header = table, true
+		default:
+			parts, rest := parseKeyPath(trimmed)
+			if parts == nil || !strings.HasPrefix(rest, "=") {
+				continue
+			}
+			l.key, l.indent = parts, indent
+			l.value = len(text) - len(rest) + 1
+			value := strings.TrimLeft(rest[1:], " \t")
+			for _, delim := range []string{`"""`, `'''`} {
+				if strings.HasPrefix(value, delim) && !strings.Contains(value[3:], delim) {
+					closing = delim
+				}
+			}
+		}
+		lines[len(lines)-1] = l
+	}
+	return lines
+}
+
+// parseKeyPath parses a dotted TOML key from the start of s and returns its
+// parts and the text after it, with leading whitespace removed.
+func parseKeyPath(s string) ([]string, string) {
+	var parts []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		var part string
+		switch {
+		case strings.HasPrefix(s, `"`):
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, ""
+			}
+			part, s = s[1:end], s[end+1:]
+		case strings.HasPrefix(s, "'"):
+			end := strings.IndexByte(s[1:], '\'')
+			if end == -1 {
+				return nil, ""
+			}
+			part, s = s[1:end+1], s[end+2:]
+		default:
+			end := 0
+			for end < len(s) && isBareKeyChar(s[end]) {
+				end++
+			}
+			if end == 0 {
+				return nil, ""
+			}
+			part, s = s[:end], s[end:]
+		}
+		parts = append(parts, part)
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, ".") {
+			return parts, s
+		}
+		s = s[1:]
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
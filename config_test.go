@@ -0,0 +1,465 @@
+package riffkey
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadBindingsReport(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+	r.HandleNamed("top", "gg", func(m Match) {})
+	r.HandleNamed("bottom", "G", func(m Match) {})
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	config := `[aliases]
+Leader = ","
+
+[global]
+quit = "Q"
+save = "<C-s>"
+
+[myapp]
+quit = "<Leader>q"
+quitt = "x"
+save = 42
+top = "<Hoem>"
+  bottom = "<Leader>q"
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diags, err := r.LoadBindingsReport(path, "myapp")
+	if err != nil {
+		t.Fatalf("LoadBindingsReport error: %v", err)
+	}
+
+	want := []struct {
+		line, column int
+		section      string
+		name         string
+		outcome      Outcome
+	}{
+		{2, 1, "aliases", "Leader", OutcomeApplied},
+		{5, 1, "global", "quit", OutcomeOverridden},
+		{6, 1, "global", "save", OutcomeApplied},
+		{9, 1, "myapp", "quit", OutcomeConflict},
+		{10, 1, "myapp", "quitt", OutcomeUnknownAction},
+		{11, 1, "myapp", "save", OutcomeInvalidValue},
+		{12, 1, "myapp", "top", OutcomeInvalidPattern},
+		{13, 3, "myapp", "bottom", OutcomeConflict},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(want), diags)
+	}
+	for i, w := range want {
+		d := diags[i]
+		if d.Line != w.line || d.Column != w.column || d.Section != w.section || d.Name != w.name || d.Outcome != w.outcome {
+			t.Errorf("diags[%d] = %v, want %d:%d [%s] %s: %v", i, d, w.line, w.column, w.section, w.name, w.outcome)
+		}
+	}
+
+	if got := diags[7].Detail; got != "same keys as quit" {
+		t.Errorf("conflict detail = %q", got)
+	}
+	if got := diags[6].String(); got != path+":12:1: [myapp] top: invalid pattern (unknown key <Hoem>)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// lenient: the invalid pattern is still applied as literal characters
+	if got := r.BindingsMap()["top"]; got != "<Hoem>" {
+		t.Errorf("top = %q, want <Hoem>", got)
+	}
+}
+
+func TestLoadBindingsReportMissingSection(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	if err := os.WriteFile(path, []byte("[other]\nquit = \"Q\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diags, err := r.LoadBindingsReport(path, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Outcome != OutcomeMissingSection || diags[0].Section != "myapp" {
+		t.Errorf("diags = %v, want a missing [myapp] section", diags)
+	}
+}
+
+func TestNotationCheck(t *testing.T) {
+	tests := []struct {
+		n       Notation
+		pattern string
+		ok      bool
+	}{
+		{NotationVim, "<C-w>j", true},
+		{NotationVim, "<lt>a<gt>", true},
+		{NotationVim, "a<b", true},
+		{NotationVim, "<>", true},
+		{NotationVim, "<Hoem>", false},
+		{NotationVim, "", false},
+		{NotationEmacs, "C-x <up>", true},
+		{NotationEmacs, "C-x <upp>", false},
+		{NotationVSCode, "ctrl+k ctrl++", true},
+		{NotationVSCode, "ctl+k", false},
+	}
+	for _, tt := range tests {
+		err := tt.n.check(tt.pattern)
+		if (err == nil) != tt.ok {
+			t.Errorf("%v.check(%q) = %v, want ok=%v", tt.n, tt.pattern, err, tt.ok)
+		}
+	}
+}
+
+func TestConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_DIRS", "/etc/org:/etc/xdg")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "home"))
+	t.Setenv("RIFFKEY_CONFIG", "/tmp/a.toml"+string(filepath.ListSeparator)+"/tmp/b.toml")
+
+	project := filepath.Join(dir, "repo")
+	if err := os.MkdirAll(filepath.Join(project, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, ".riffkey.toml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(filepath.Join(project, "sub"))
+
+	want := []string{
+		"/etc/xdg/riffkey.toml",
+		"/etc/org/riffkey.toml",
+		filepath.Join(dir, "home", "riffkey.toml"),
+		filepath.Join(project, ".riffkey.toml"),
+		"/tmp/a.toml",
+		"/tmp/b.toml",
+	}
+	if got := ConfigPaths(); !slices.Equal(got, want) {
+		t.Errorf("ConfigPaths() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLoadBindingsFromPathsPrecedence(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+	r.HandleNamed("help", "?", func(m Match) {})
+
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.toml")
+	user := filepath.Join(dir, "user.toml")
+	writeConfig(t, system, "[myapp]\nquit = \"Q\"\nsave = \"S\"\n")
+	writeConfig(t, user, "[global]\nquit = \"<C-q>\"\n")
+
+	diags, err := r.LoadBindingsFromPaths([]string{system, user, filepath.Join(dir, "missing.toml")}, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a later file wins even over an earlier file's app section
+	sources := make(map[string]Binding)
+	for _, b := range r.Bindings() {
+		sources[b.Name] = b
+	}
+	if b := sources["quit"]; b.Pattern != "<C-q>" || b.Source != user {
+		t.Errorf("quit = %q from %q, want <C-q> from user file", b.Pattern, b.Source)
+	}
+	if b := sources["save"]; b.Pattern != "S" || b.Source != system {
+		t.Errorf("save = %q from %q, want S from system file", b.Pattern, b.Source)
+	}
+	if b := sources["help"]; b.Source != "" {
+		t.Errorf("help source = %q, want empty for a default", b.Source)
+	}
+
+	if len(diags) != 3 || diags[0].Name != "quit" || diags[0].Outcome != OutcomeOverridden {
+		t.Fatalf("diags = %v", diags)
+	}
+	if want := "by " + user + " [global]"; diags[0].Detail != want {
+		t.Errorf("override detail = %q, want %q", diags[0].Detail, want)
+	}
+
+	r.Reset("quit")
+	if b := r.Bindings()[0]; b.Source != "" {
+		t.Errorf("Reset should clear the source, got %q", b.Source)
+	}
+}
+
+func TestLoadBindingsFromPathsMalformedLeavesRouter(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.toml")
+	bad := filepath.Join(dir, "bad.toml")
+	writeConfig(t, good, "[myapp]\nquit = \"Q\"\n")
+	writeConfig(t, bad, "[myapp\n")
+
+	if _, err := r.LoadBindingsFromPaths([]string{good, bad}, "myapp"); err == nil {
+		t.Fatal("expected a parse error")
+	}
+	if got := r.BindingsMap()["quit"]; got != "q" {
+		t.Errorf("quit = %q, a malformed file should leave the router untouched", got)
+	}
+}
+
+func TestLoadBindingsInclude(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	shared := filepath.Join(dir, "shared", "team.toml")
+	main := filepath.Join(dir, "riffkey.toml")
+	writeConfig(t, shared, "include = \"../riffkey.toml\"\n[myapp]\nquit = \"Q\"\nsave = \"S\"\n")
+	writeConfig(t, main, "include = [\"shared/team.toml\", \"nope.toml\"]\n\n[myapp]\nquit = \"<C-q>\"\n")
+
+	diags, err := r.LoadBindingsFromPaths([]string{main}, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := r.BindingsMap()
+	if m["quit"] != "<C-q>" || m["save"] != "S" {
+		t.Errorf("bindings = %v, the including file should win over its include", m)
+	}
+	if b := r.Bindings()[1]; b.Source != shared {
+		t.Errorf("save source = %q, want %q", b.Source, shared)
+	}
+
+	var missing []Diagnostic
+	for _, d := range diags {
+		if d.Name == "include" {
+			missing = append(missing, d)
+		}
+	}
+	if len(missing) != 1 || missing[0].Value != filepath.Join(dir, "nope.toml") || missing[0].Line != 1 {
+		t.Errorf("missing include diagnostics = %v", missing)
+	}
+}
+
+func TestLoadBindingsModes(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	normal := NewRouter().Name("normal")
+	normal.HandleNamed("down", "j", func(m Match) {})
+	normal.HandleNamed("up", "k", func(m Match) {})
+	insert := NewRouter().Name("insert")
+	insert.HandleNamed("escape", "<Esc>", func(m Match) {})
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, `[aliases]
+Leader = ","
+
+[global]
+quit = "Q"
+
+[global.normal]
+down = "n"
+up = "e"
+
+[myapp]
+quit = "<Leader>q"
+
+[myapp.normal]
+down = "<Down>"
+
+[myapp.insert]
+escape = "<C-c>"
+`)
+
+	diags, err := r.LoadBindingsReport(path, "myapp", normal, insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diags {
+		if d.Outcome == OutcomeUnknownAction {
+			t.Errorf("mode section read as bindings: %v", d)
+		}
+	}
+
+	if got := r.BindingsMap()["quit"]; got != "<Leader>q" {
+		t.Errorf("quit = %q", got)
+	}
+	if m := normal.BindingsMap(); m["down"] != "<Down>" || m["up"] != "e" {
+		t.Errorf("normal = %v, want [myapp.normal] over [global.normal]", m)
+	}
+	if got := insert.BindingsMap()["escape"]; got != "<C-c>" {
+		t.Errorf("insert escape = %q", got)
+	}
+	if insert.aliases["leader"] != "," {
+		t.Error("[aliases] should apply to mode routers")
+	}
+
+	var sections []string
+	for _, d := range diags {
+		sections = append(sections, d.Section)
+	}
+	want := []string{"aliases", "global", "global.normal", "global.normal", "myapp", "myapp.normal", "myapp.insert"}
+	if !slices.Equal(sections, want) {
+		t.Errorf("diagnostic sections = %q, want %q", sections, want)
+	}
+}
+
+func TestLoadBindingsNamedRouterOwnSection(t *testing.T) {
+	r := NewRouter().Name("normal")
+	r.HandleNamed("down", "j", func(m Match) {})
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp.normal]\ndown = \"n\"\n")
+	if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.BindingsMap()["down"]; got != "n" {
+		t.Errorf("down = %q, want n from [myapp.normal]", got)
+	}
+}
+
+func TestWriteDefaultBindingsModes(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	normal := NewRouter().Name("normal")
+	normal.HandleNamed("down", "j", func(m Match) {})
+	insert := NewRouter().Name("insert")
+	insert.HandleNamed("escape", "<Esc>", func(m Match) {})
+
+	var buf strings.Builder
+	if err := r.WriteDefaultBindings(&buf, "myapp", normal, insert, NewRouter()); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Uncomment a line to rebind it; set it to "" or false to unbind.
+
+[myapp]
+# quit = "q"
+
+[myapp.normal]
+# down = "j"
+
+[myapp.insert]
+# escape = "<Esc>"
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestLoadBindingsUnbind(t *testing.T) {
+	r := NewRouter()
+	var deleted bool
+	r.HandleNamed("delete_line", "dd", func(m Match) { deleted = true })
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+	git := NewRouter().Name("git")
+	git.HandleNamed("push", "P", func(m Match) {})
+	r.Mount("<Space>g", git)
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp]\ndelete_line = false\nquit = \"\"\nsave = true\ngit.push = \"\"\n")
+	diags, err := r.LoadBindingsReport(path, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outcomes := make(map[string]string)
+	for _, d := range diags {
+		outcomes[d.Name] = d.Outcome.String() + " " + d.Detail
+	}
+	want := map[string]string{
+		"delete_line": "applied unbound",
+		"quit":        "applied unbound",
+		"save":        "invalid value ",
+		"git.push":    "applied unbound",
+	}
+	for name, w := range want {
+		if outcomes[name] != w {
+			t.Errorf("%s: %q, want %q", name, outcomes[name], w)
+		}
+	}
+
+	unbound := make(map[string]bool)
+	for _, b := range r.Bindings() {
+		unbound[b.Name] = b.Unbound()
+	}
+	if !unbound["delete_line"] || !unbound["quit"] || unbound["save"] || !unbound["git.push"] {
+		t.Errorf("Unbound() = %v", unbound)
+	}
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'd'})
+	in.Dispatch(Key{Rune: 'd'})
+	if deleted {
+		t.Error("unbound dd should not fire")
+	}
+
+	r.Reset("delete_line")
+	in.Dispatch(Key{Rune: 'd'})
+	in.Dispatch(Key{Rune: 'd'})
+	if !deleted {
+		t.Error("Reset should restore dd")
+	}
+	if b := r.Bindings()[0]; b.Unbound() || b.Pattern != "dd" {
+		t.Errorf("after Reset: %+v", b)
+	}
+}
+
+func TestLoadBindingsPublishesOnce(t *testing.T) {
+	// Each config moves every binding onto another's keys, so applying it
+	// one entry at a time leaves keys unbound or shared along the way.
+	dir := t.TempDir()
+	keys := "abcdefgh"
+	var paths []string
+	for shift := range 2 {
+		config := "[myapp]\n"
+		for i := range keys {
+			config += fmt.Sprintf("b%d = %q\n", i, keys[(i+shift+1)%len(keys)])
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%d.toml", shift))
+		writeConfig(t, path, config)
+		paths = append(paths, path)
+	}
+
+	r := NewRouter()
+	for i := range keys {
+		r.HandleNamed(fmt.Sprintf("b%d", i), keys[i:i+1], func(m Match) {})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 500 {
+			if _, err := r.LoadBindingsFromPaths(paths[i%2:i%2+1], "myapp"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		s := r.snapshot() // what one key is matched against
+		names := make(map[string]bool)
+		for _, k := range keys {
+			if _, name, _, _ := s.matchTrie([]Key{{Rune: k}}, nil); name != "" {
+				names[name] = true
+			}
+		}
+		if len(names) != len(keys) {
+			t.Fatalf("half-applied config: keys reach only %d bindings", len(names))
+		}
+	}
+}
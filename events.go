@@ -0,0 +1,160 @@
+package riffkey
+
+import "slices"
+
+// EventKind identifies a step of key dispatch reported to subscribers.
+type EventKind uint8
+
+const (
+	EventKey       EventKind = iota // a key was received by Dispatch
+	EventCount                      // the key was a count-prefix digit
+	EventPartial                    // the key extended a sequence that has no handler yet
+	EventAmbiguous                  // a handler matched but a longer pattern could; waiting on the timeout
+	EventHandler                    // a handler fired
+	EventTimeout                    // the ambiguity timeout fired; an EventHandler follows
+	EventBroken                     // the key broke a pending sequence, which was discarded
+	EventUnmatched                  // the key matched nothing
+	EventMode                       // the current mode changed
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventKey:
+		return "key"
+	case EventCount:
+		return "count"
+	case EventPartial:
+		return "partial"
+	case EventAmbiguous:
+		return "ambiguous"
+	case EventHandler:
+		return "handler"
+	case EventTimeout:
+		return "timeout"
+	case EventBroken:
+		return "broken"
+	case EventUnmatched:
+		return "unmatched"
+	case EventMode:
+		return "mode"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes one step of key dispatch. Fields that don't apply to the
+// kind are zero.
+type Event struct {
+	Kind    EventKind
+	Key     Key    // the key being dispatched; zero for timeout and Flush events
+	Keys    []Key  // the pending sequence, or the keys a handler matched
+	Count   int    // the count prefix; for EventCount, the count typed so far
+	Binding string // name of the binding involved, if it is named
+	Router  string // name of the frame router that matched
+
+	// For EventMode, the current mode and the one before it (see Input.Mode)
+	Mode     string
+	PrevMode string
+}
+
+// subscriber is a registered event callback, compared by identity.
+type subscriber struct {
+	fn func(Event)
+}
+
+// Subscribe registers fn to receive every dispatch event and returns a
+// function that removes it. Any number of subscribers may be registered;
+// each sees events in the order they happened.
+//
+// Events are delivered on the dispatching goroutine (the timeout goroutine
+// for EventTimeout, the one that changed the stack for EventMode) without
+// Input's lock held, so fn may call back into the Input. An EventHandler is
+// delivered before its handler runs, unless another goroutine is delivering
+// events at the time.
+//
+// Example, a showcmd line:
+//
+//	input.Subscribe(func(e riffkey.Event) {
+//	    switch e.Kind {
+//	    case riffkey.EventPartial, riffkey.EventAmbiguous:
+//	        showcmd = riffkey.FormatPattern(e.Keys)
+//	    case riffkey.EventHandler, riffkey.EventBroken, riffkey.EventUnmatched:
+//	        showcmd = ""
+//	    }
+//	})
+func (i *Input) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s := &subscriber{fn: fn}
+	i.mu.Lock()
+	i.subscribers = append(i.subscribers, s)
+	i.mu.Unlock()
+	return func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		i.subscribers = slices.DeleteFunc(slices.Clone(i.subscribers), func(o *subscriber) bool { return o == s })
+	}
+}
+
+// emit queues an event for delivery. Caller must hold i.mu.
+func (i *Input) emit(e Event) {
+	if len(i.subscribers) > 0 {
+		i.events = append(i.events, e)
+	}
+}
+
+// deliver sends queued events to the subscribers, resolved matches to the
+// match channel and handler invocations to the executor. Caller must not hold i.mu. If delivery is
+// already underway further up the stack (a subscriber or handler dispatched
+// a key) or on another goroutine, the new items are left for that delivery,
+// which keeps them in order.
+func (i *Input) deliver() {
+	i.mu.Lock()
+	if i.delivering {
+		i.mu.Unlock()
+		return
+	}
+	i.delivering = true
+	locked := true
+	defer func() {
+		// also runs if a subscriber or handler panics, so that later
+		// deliveries aren't left waiting on this one
+		if !locked {
+			i.mu.Lock()
+		}
+		i.delivering = false
+		i.mu.Unlock()
+	}()
+	for len(i.events) > 0 || len(i.resolved) > 0 || len(i.jobs) > 0 {
+		events, subs := i.events, i.subscribers
+		resolved, ch := i.resolved, i.matches
+		jobs, exec := i.jobs, i.executor
+		i.events, i.resolved, i.jobs = nil, nil, nil
+		locked = false
+		i.mu.Unlock()
+		for _, e := range events {
+			for _, s := range subs {
+				s.fn(e)
+			}
+		}
+		for _, r := range resolved {
+			ch <- r
+		}
+		for _, fn := range jobs {
+			if exec != nil {
+				exec(fn)
+			} else {
+				fn()
+			}
+		}
+		i.mu.Lock()
+		locked = true
+	}
+}
+
+// unlocked runs fn with i.mu released, after delivering queued events.
+// Caller must hold i.mu.
+func (i *Input) unlocked(fn func()) {
+	i.mu.Unlock()
+	defer i.mu.Lock()
+	i.deliver()
+	fn()
+}
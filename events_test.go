@@ -0,0 +1,109 @@
+package riffkey
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	r := NewRouter().Name("normal")
+	r.HandleNamed("top", "gg", func(m Match) {})
+	r.HandleNamed("delete", "d", func(m Match) {})
+	r.Handle("dd", func(m Match) {})
+	in := NewInput(r)
+
+	var got []Event
+	in.Subscribe(func(e Event) { got = append(got, e) })
+
+	kinds := func() []EventKind {
+		var ks []EventKind
+		for _, e := range got {
+			ks = append(ks, e.Kind)
+		}
+		got = nil
+		return ks
+	}
+
+	in.Dispatch(Key{Rune: '3'})
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 'g'})
+	want := []EventKind{EventKey, EventCount, EventKey, EventPartial, EventKey, EventHandler}
+	events := slices.Clone(got)
+	if ks := kinds(); !slices.Equal(ks, want) {
+		t.Fatalf("kinds = %v, want %v", ks, want)
+	}
+	fired := events[5]
+	if fired.Binding != "top" || fired.Router != "normal" || fired.Count != 3 || FormatPattern(fired.Keys) != "gg" {
+		t.Errorf("handler event = %+v", fired)
+	}
+
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 'x'})
+	if ks := kinds(); !slices.Equal(ks, []EventKind{EventKey, EventPartial, EventKey, EventUnmatched}) {
+		t.Errorf("no-match kinds = %v", ks)
+	}
+
+	in.Dispatch(Key{Rune: 'd'})
+	in.Dispatch(Key{Rune: 'x'})
+	if ks := kinds(); !slices.Equal(ks, []EventKind{EventKey, EventAmbiguous, EventKey, EventBroken, EventUnmatched}) {
+		t.Errorf("broken kinds = %v", ks)
+	}
+
+	in.Dispatch(Key{Rune: 'd'})
+	in.Flush()
+	events = slices.Clone(got)
+	if ks := kinds(); !slices.Equal(ks, []EventKind{EventKey, EventAmbiguous, EventHandler}) {
+		t.Errorf("flush kinds = %v", ks)
+	}
+	if events[2].Binding != "delete" {
+		t.Errorf("flushed binding = %q", events[2].Binding)
+	}
+}
+
+func TestSubscribeTimeout(t *testing.T) {
+	r := NewRouter().Timeout(10 * time.Millisecond)
+	r.HandleNamed("delete", "d", func(m Match) {})
+	r.Handle("dd", func(m Match) {})
+	in := NewInput(r)
+
+	events := make(chan Event, 10)
+	in.Subscribe(func(e Event) { events <- e })
+	in.Dispatch(Key{Rune: 'd'})
+
+	var kinds []EventKind
+	timeout := time.After(time.Second)
+	for len(kinds) < 4 {
+		select {
+		case e := <-events:
+			kinds = append(kinds, e.Kind)
+		case <-timeout:
+			t.Fatalf("kinds = %v, timed out", kinds)
+		}
+	}
+	if !slices.Equal(kinds, []EventKind{EventKey, EventAmbiguous, EventTimeout, EventHandler}) {
+		t.Errorf("kinds = %v", kinds)
+	}
+}
+
+func TestSubscribeMultipleAndUnsubscribe(t *testing.T) {
+	r := NewRouter()
+	r.Handle("j", func(m Match) {})
+	in := NewInput(r)
+
+	var a, b int
+	unsubA := in.Subscribe(func(e Event) { a++ })
+	in.Subscribe(func(e Event) {
+		b++
+		if e.Kind == EventHandler {
+			in.Pending() // subscribers may call back into the Input
+		}
+	})
+
+	in.Dispatch(Key{Rune: 'j'})
+	unsubA()
+	in.Dispatch(Key{Rune: 'j'})
+	if a != 2 || b != 4 {
+		t.Errorf("a = %d, b = %d, want 2 and 4", a, b)
+	}
+}
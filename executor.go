@@ -0,0 +1,231 @@
+package riffkey
+
+import "sync"
+
+// SetExecutor makes the Input pass every handler invocation to exec instead
+// of calling it: matched handlers together with their hooks, whether they
+// fire on a key, on the ambiguity timeout or through Flush, and HandleUnmatched
+// fallbacks. exec receives them one at a time in dispatch order, without
+// Input's lock held, and runs them or arranges for them to run, typically
+// on the app's own goroutine. Since the timeout fires on a goroutine of its
+// own, this is how an app gets every handler onto a single goroutine.
+//
+// exec is called from whichever goroutine is dispatching, which may be the
+// one that runs handlers (a handler that executes a macro or calls Flush),
+// so it must not block waiting for that goroutine. A Queue never blocks.
+//
+// With an executor, Dispatch can't wait for an unmatched fallback to report
+// whether it consumed the key, so it reports true whenever there is one.
+// Pass nil to call handlers directly again.
+//
+// Handlers run after Dispatch returns, so a Push or Pop in a handler only
+// takes effect once the executor runs it: keys dispatched in the meantime
+// are matched against the frames as they were. If "i" pushes insert mode and
+// "hello" arrives before the queue is drained, "h" runs in normal mode, and
+// a change that pushed a frame (see Repeat) ends early. Modal apps should
+// call SetHoldKeys(true) so such keys wait for the handler.
+//
+// Example, with Bubble Tea:
+//
+//	q := riffkey.NewQueue()
+//	input.SetExecutor(q.Submit)
+//	go func() {
+//	    for range q.Ready() {
+//	        p.Send(runHandlersMsg{})
+//	    }
+//	}()
+//
+//	// in Update
+//	case runHandlersMsg:
+//	    q.Drain()
+func (i *Input) SetExecutor(exec func(fn func())) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.executor = exec
+}
+
+// SetHoldKeys makes Dispatch hold keys while handlers it passed to the
+// executor haven't run yet, and dispatch them in order once the last one
+// has, on the goroutine that ran it. Keys are then matched against the
+// frames the handlers left, as they are without an executor: "i" followed
+// quickly by "hello" types "hello" in the insert mode "i" pushes. Dispatch
+// reports true for a held key. Keys of macro playback and Repeat are held
+// too, and dispatched as part of the playback or repeat that sent them.
+//
+// Holding has no effect without an executor. Turning it off dispatches any
+// keys still held.
+//
+// Example:
+//
+//	q := riffkey.NewQueue()
+//	input.SetExecutor(q.Submit)
+//	input.SetHoldKeys(true)
+func (i *Input) SetHoldKeys(on bool) {
+	i.mu.Lock()
+	i.holdKeys = on
+	i.mu.Unlock()
+	i.release()
+}
+
+// heldKey is a key held by SetHoldKeys, with the macro playback depth and
+// repeat it was dispatched in.
+type heldKey struct {
+	key       Key
+	executing int
+	repeating bool
+}
+
+// holding reports whether Dispatch must hold its key: keys are already
+// held, or handlers are outstanding. Caller must hold i.mu.
+func (i *Input) holding() bool {
+	return len(i.held) > 0 || i.holdKeys && i.inflight > 0
+}
+
+// release dispatches held keys until none are left or a handler they fire
+// is outstanding.
+func (i *Input) release() {
+	for {
+		i.mu.Lock()
+		if len(i.held) == 0 || i.holdKeys && i.inflight > 0 {
+			i.mu.Unlock()
+			return
+		}
+		h := i.held[0]
+		i.held = i.held[1:]
+		executing, repeating := i.executing, i.repeating
+		i.executing, i.repeating = h.executing, h.repeating
+		i.dispatch(h.key)
+		i.mu.Lock()
+		i.executing, i.repeating = executing, repeating
+		i.mu.Unlock()
+	}
+}
+
+// invoke runs fn, a handler with its hooks: through the executor if there
+// is one, otherwise right away with i.mu released. Caller must hold i.mu.
+func (i *Input) invoke(fn func()) {
+	if i.executor != nil {
+		i.jobs = append(i.jobs, i.tracked(i.nested(fn)))
+		return
+	}
+	i.unlocked(fn)
+}
+
+// tracked returns fn counted as outstanding until it has run, so that keys
+// are held meanwhile, if SetHoldKeys is on. Caller must hold i.mu.
+func (i *Input) tracked(fn func()) func() {
+	if !i.holdKeys {
+		return fn
+	}
+	i.inflight++
+	return func() {
+		func() {
+			defer func() {
+				i.mu.Lock()
+				i.inflight--
+				i.mu.Unlock()
+			}()
+			fn()
+		}()
+		i.release()
+	}
+}
+
+// nested returns fn set to run at the current depth of macro playback, so
+// that a queued handler that plays a macro sees how deeply it is nested
+// even though the playback that fired it has returned by the time it runs.
+// Caller must hold i.mu.
+func (i *Input) nested(fn func()) func() {
+	depth := i.executing
+	if depth == 0 {
+		return fn
+	}
+	return func() {
+		i.mu.Lock()
+		prev := i.executing
+		i.executing = depth
+		i.mu.Unlock()
+		defer func() {
+			i.mu.Lock()
+			i.executing = prev
+			i.mu.Unlock()
+		}()
+		fn()
+	}
+}
+
+// invokeUnmatched runs an unmatched fallback for key as invoke does and
+// reports whether it consumed the key. Caller must hold i.mu.
+func (i *Input) invokeUnmatched(um func(Key) bool, key Key) bool {
+	keys := []Key{key}
+	if i.executor != nil {
+		i.jobs = append(i.jobs, i.tracked(i.nested(i.guard("", keys, func() { um(key) }))))
+		return true
+	}
+	var handled bool
+	i.unlocked(i.guard("", keys, func() { handled = um(key) }))
+	return handled
+}
+
+// Queue is an unbounded first-in, first-out queue of functions, for running
+// handlers on one goroutine: pass Submit to Input.SetExecutor and call
+// Drain from the goroutine that owns the app's state. Submit never blocks.
+//
+// Example, in a select loop:
+//
+//	q := riffkey.NewQueue()
+//	input.SetExecutor(q.Submit)
+//	for {
+//	    select {
+//	    case <-q.Ready():
+//	        q.Drain()
+//	    case ev := <-network:
+//	        handle(ev)
+//	    }
+//	}
+type Queue struct {
+	mu    sync.Mutex
+	fns   []func()
+	ready chan struct{}
+}
+
+// NewQueue creates an empty Queue.
+func NewQueue() *Queue {
+	return &Queue{ready: make(chan struct{}, 1)}
+}
+
+// Submit adds fn to the queue and signals Ready.
+func (q *Queue) Submit(fn func()) {
+	q.mu.Lock()
+	q.fns = append(q.fns, fn)
+	q.mu.Unlock()
+	select {
+	case q.ready <- struct{}{}:
+	default: // already signalled
+	}
+}
+
+// Ready returns a channel that receives a value when functions have been
+// submitted since the last receive. One receive may cover several Submits.
+func (q *Queue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Drain runs the queued functions in order on the calling goroutine,
+// including any they submit, and returns how many ran.
+func (q *Queue) Drain() int {
+	n := 0
+	for {
+		q.mu.Lock()
+		fns := q.fns
+		q.fns = nil
+		q.mu.Unlock()
+		if len(fns) == 0 {
+			return n
+		}
+		for _, fn := range fns {
+			fn()
+		}
+		n += len(fns)
+	}
+}
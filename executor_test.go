@@ -0,0 +1,162 @@
+package riffkey
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExecutorQueuesHandlers(t *testing.T) {
+	var ran []string
+	r := NewRouter().Timeout(10 * time.Millisecond)
+	r.AddOnAfter(func() { ran = append(ran, "after") })
+	r.Handle("j", func(m Match) { ran = append(ran, "j") })
+	r.Handle("d", func(m Match) { ran = append(ran, "d") })
+	r.Handle("dd", func(m Match) { ran = append(ran, "dd") })
+	r.Handle("g", func(m Match) { ran = append(ran, "g") })
+	r.Handle("gg", func(m Match) { ran = append(ran, "gg") })
+	r.HandleUnmatched(func(k Key) bool { ran = append(ran, "unmatched "+string(k.Rune)); return true })
+	in := NewInput(r)
+
+	q := NewQueue()
+	in.SetExecutor(q.Submit)
+
+	in.Dispatch(Key{Rune: 'j'})
+	if !in.Dispatch(Key{Rune: 'x'}) {
+		t.Error("Dispatch with an unmatched fallback and an executor should report true")
+	}
+	in.Dispatch(Key{Rune: 'd'})
+	in.Flush()
+	if len(ran) != 0 {
+		t.Fatalf("handlers ran before Drain: %v", ran)
+	}
+
+	in.Dispatch(Key{Rune: 'g'}) // fires on the timeout, through the queue
+	n := 0
+	for n < 4 {
+		select {
+		case <-q.Ready():
+			n += q.Drain()
+		case <-time.After(time.Second):
+			t.Fatalf("ran %d queued functions, want 4", n)
+		}
+	}
+	want := []string{"j", "after", "unmatched x", "d", "after", "g", "after"}
+	if !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+
+	in.SetExecutor(nil)
+	ran = nil
+	in.Dispatch(Key{Rune: 'j'})
+	if !slices.Equal(ran, []string{"j", "after"}) {
+		t.Errorf("without an executor ran %v", ran)
+	}
+}
+
+func TestQueueDrainRunsNestedSubmits(t *testing.T) {
+	r := NewRouter()
+	var ran []string
+	r.Handle("a", func(m Match) { ran = append(ran, "a") })
+	in := NewInput(r)
+	q := NewQueue()
+	in.SetExecutor(q.Submit)
+	r.Handle("@", func(m Match) {
+		ran = append(ran, "@")
+		in.ExecuteMacro(Macro{{Rune: 'a'}, {Rune: 'a'}})
+	})
+
+	in.Dispatch(Key{Rune: '@'})
+	if n := q.Drain(); n != 3 {
+		t.Errorf("Drain ran %d functions, want 3", n)
+	}
+	if !slices.Equal(ran, []string{"@", "a", "a"}) {
+		t.Errorf("ran %v", ran)
+	}
+}
+
+func TestHoldKeysUntilHandlersRun(t *testing.T) {
+	var typed []rune
+	var ran []string
+	normal := NewRouter()
+	insert := NewRouter()
+	in := NewInput(normal)
+	normal.HandleNamed("insert", "i", func(m Match) { in.Push(insert) })
+	normal.HandleNamed("change_word", "cw", func(m Match) { in.Push(insert) })
+	normal.Repeatable("change_word")
+	normal.Handle("h", func(m Match) { ran = append(ran, "h") })
+	insert.Handle("<Esc>", func(m Match) { in.Pop() })
+	insert.HandleUnmatched(func(k Key) bool {
+		typed = append(typed, k.Rune)
+		return true
+	})
+
+	q := NewQueue()
+	in.SetExecutor(q.Submit)
+	in.SetHoldKeys(true)
+
+	// keys typed before the queue is drained wait for "i" to push insert
+	for _, k := range "ihello" {
+		if !in.Dispatch(Key{Rune: k}) {
+			t.Errorf("Dispatch(%c) = false for a held key", k)
+		}
+	}
+	q.Drain()
+	if string(typed) != "hello" || len(ran) != 0 {
+		t.Errorf("typed %q, normal ran %v; want hello typed in insert mode", string(typed), ran)
+	}
+	in.Dispatch(Key{Special: SpecialEscape})
+	q.Drain()
+
+	// the held keys are part of the change, so they repeat with it
+	typed = nil
+	for _, k := range "cwab" {
+		in.Dispatch(Key{Rune: k})
+	}
+	in.Dispatch(Key{Special: SpecialEscape})
+	q.Drain()
+	in.Repeat(0)
+	q.Drain()
+	if string(typed) != "abab" || in.Depth() != 1 {
+		t.Errorf("typed %q at depth %d, want abab at depth 1", string(typed), in.Depth())
+	}
+}
+
+func TestHoldKeysDuringMacroPlayback(t *testing.T) {
+	var typed []rune
+	var ran []string
+	normal := NewRouter()
+	insert := NewRouter()
+	in := NewInput(normal)
+	inserts := 0
+	normal.Handle("i", func(m Match) { inserts++; in.Push(insert) })
+	normal.Handle("h", func(m Match) { ran = append(ran, "h") })
+	normal.Handle("@a", func(m Match) { in.ExecuteRegister("a", 1) })
+	insert.Handle("<Esc>", func(m Match) { in.Pop() })
+	insert.HandleUnmatched(func(k Key) bool {
+		typed = append(typed, k.Rune)
+		return true
+	})
+
+	q := NewQueue()
+	in.SetExecutor(q.Submit)
+	in.SetHoldKeys(true)
+
+	in.ExecuteMacro(ParsePattern("ihi<Esc>"))
+	q.Drain()
+	if string(typed) != "hi" || len(ran) != 0 || inserts != 1 || in.Depth() != 1 {
+		t.Errorf("typed %q, normal ran %v, i ran %d times, depth %d; want hi typed in insert mode",
+			string(typed), ran, inserts, in.Depth())
+	}
+
+	// held playback keys keep their depth, so a macro that runs itself
+	// still stops
+	typed = nil
+	in.SetMacroDepth(3)
+	in.SetRegister("a", ParsePattern("ix<Esc>@a"))
+	in.ExecuteRegister("a", 1)
+	q.Drain()
+	if string(typed) != "xxx" || in.Depth() != 1 {
+		t.Errorf("typed %q at depth %d, want xxx at depth 1", string(typed), in.Depth())
+	}
+}
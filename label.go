@@ -0,0 +1,172 @@
+package riffkey
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LabelStyle renders keys for on-screen help: footers, status lines and
+// cheat sheets. Unlike pattern notations, labels are for display only and
+// are not meant to be parsed back.
+type LabelStyle uint8
+
+const (
+	LabelText    LabelStyle = iota // "Ctrl+Alt+D", "Shift+Tab", "Up"
+	LabelSymbols                   // macOS style: "⌃⌥D", "⇧⇥", "↑"
+	LabelCompact                   // terse text: "^D", "M-x", "PgUp"
+)
+
+// KeyFormatter formats a key sequence for display. Notation and LabelStyle
+// both implement it.
+type KeyFormatter interface {
+	Format(keys []Key) string
+}
+
+var specialLabels = map[LabelStyle]map[Special]string{
+	LabelText: {
+		SpecialEscape:    "Esc",
+		SpecialEnter:     "Enter",
+		SpecialTab:       "Tab",
+		SpecialSpace:     "Space",
+		SpecialBackspace: "Backspace",
+		SpecialUp:        "Up",
+		SpecialDown:      "Down",
+		SpecialLeft:      "Left",
+		SpecialRight:     "Right",
+		SpecialHome:      "Home",
+		SpecialEnd:       "End",
+		SpecialPageUp:    "PageUp",
+		SpecialPageDown:  "PageDown",
+		SpecialInsert:    "Insert",
+		SpecialDelete:    "Delete",
+	},
+	LabelSymbols: {
+		SpecialEscape:    "⎋",
+		SpecialEnter:     "↩",
+		SpecialTab:       "⇥",
+		SpecialSpace:     "␣",
+		SpecialBackspace: "⌫",
+		SpecialUp:        "↑",
+		SpecialDown:      "↓",
+		SpecialLeft:      "←",
+		SpecialRight:     "→",
+		SpecialHome:      "↖",
+		SpecialEnd:       "↘",
+		SpecialPageUp:    "⇞",
+		SpecialPageDown:  "⇟",
+		SpecialInsert:    "Ins",
+		SpecialDelete:    "⌦",
+	},
+	LabelCompact: {
+		SpecialEscape:    "Esc",
+		SpecialEnter:     "Ret",
+		SpecialTab:       "Tab",
+		SpecialSpace:     "Spc",
+		SpecialBackspace: "BS",
+		SpecialUp:        "↑",
+		SpecialDown:      "↓",
+		SpecialLeft:      "←",
+		SpecialRight:     "→",
+		SpecialHome:      "Home",
+		SpecialEnd:       "End",
+		SpecialPageUp:    "PgUp",
+		SpecialPageDown:  "PgDn",
+		SpecialInsert:    "Ins",
+		SpecialDelete:    "Del",
+	},
+}
+
+// FormatKey returns the label for a single key. Paste events are labelled
+// "Paste"; empty keys yield "".
+func (s LabelStyle) FormatKey(k Key) string {
+	if k.IsPaste() {
+		return "Paste"
+	}
+
+	mod := k.Mod
+	var name string
+	switch {
+	case k.Special >= SpecialF1 && k.Special <= SpecialF12:
+		name = k.Special.String()
+	case k.Special != SpecialNone:
+		name = specialLabels[s][k.Special]
+	case k.Rune == ' ':
+		name = specialLabels[s][SpecialSpace]
+	case k.Rune == 0:
+		return ""
+	case s == LabelSymbols:
+		// macOS shows letters in upper case with Shift spelled out
+		if unicode.IsUpper(k.Rune) {
+			mod |= ModShift
+		}
+		name = string(unicode.ToUpper(k.Rune))
+	case mod&(ModCtrl|ModSuper) != 0 || (s == LabelText && mod != ModNone):
+		// chords read better as Ctrl+D than Ctrl+d; keep Shift visible
+		if unicode.IsUpper(k.Rune) && mod&ModCtrl == 0 {
+			mod |= ModShift
+		}
+		name = string(unicode.ToUpper(k.Rune))
+	default:
+		name = string(k.Rune)
+	}
+
+	switch s {
+	case LabelSymbols:
+		var sb strings.Builder
+		for _, m := range []struct {
+			mod    Modifier
+			symbol string
+		}{{ModCtrl, "⌃"}, {ModAlt, "⌥"}, {ModShift, "⇧"}, {ModSuper, "⌘"}} {
+			if mod&m.mod != 0 {
+				sb.WriteString(m.symbol)
+			}
+		}
+		return sb.String() + name
+	case LabelCompact:
+		var sb strings.Builder
+		if mod&ModSuper != 0 {
+			sb.WriteString("D-")
+		}
+		if mod&ModAlt != 0 {
+			sb.WriteString("M-")
+		}
+		if mod&ModShift != 0 {
+			sb.WriteString("S-")
+		}
+		if mod&ModCtrl != 0 {
+			sb.WriteString("^")
+		}
+		return sb.String() + name
+	default:
+		if mod == ModNone {
+			return name
+		}
+		return mod.String() + "+" + name
+	}
+}
+
+// Format labels a key sequence. Keys are separated by spaces, except in
+// LabelCompact where they are run together ("gg", "^Wj").
+func (s LabelStyle) Format(keys []Key) string {
+	labels := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if l := s.FormatKey(k); l != "" {
+			labels = append(labels, l)
+		}
+	}
+	if s == LabelCompact {
+		return strings.Join(labels, "")
+	}
+	return strings.Join(labels, " ")
+}
+
+// Pattern labels a vim-style pattern. Use Router.BindingsIn for patterns
+// containing aliases or written in another notation.
+func (s LabelStyle) Pattern(pattern string) string {
+	return s.Format(ParsePattern(pattern))
+}
+
+// Label returns the label for k in style s.
+func (k Key) Label(s LabelStyle) string {
+	return s.FormatKey(k)
+}
@@ -0,0 +1,78 @@
+package riffkey
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabelStyles(t *testing.T) {
+	tests := []struct {
+		key                    Key
+		text, symbols, compact string
+	}{
+		{Key{Rune: 'd', Mod: ModCtrl | ModAlt}, "Ctrl+Alt+D", "⌃⌥D", "M-^D"},
+		{Key{Rune: 'w', Mod: ModCtrl}, "Ctrl+W", "⌃W", "^W"},
+		{Key{Rune: 'x', Mod: ModAlt}, "Alt+X", "⌥X", "M-x"},
+		{Key{Rune: 'X', Mod: ModAlt}, "Alt+Shift+X", "⌥⇧X", "M-X"},
+		{Key{Rune: 's', Mod: ModSuper}, "Super+S", "⌘S", "D-S"},
+		{Key{Special: SpecialTab, Mod: ModShift}, "Shift+Tab", "⇧⇥", "S-Tab"},
+		{Key{Special: SpecialUp}, "Up", "↑", "↑"},
+		{Key{Special: SpecialPageDown}, "PageDown", "⇟", "PgDn"},
+		{Key{Special: SpecialEnter}, "Enter", "↩", "Ret"},
+		{Key{Special: SpecialF5, Mod: ModCtrl}, "Ctrl+F5", "⌃F5", "^F5"},
+		{Key{Rune: 'j'}, "j", "J", "j"},
+		{Key{Rune: 'G'}, "G", "⇧G", "G"},
+		{Key{Rune: ' ', Mod: ModCtrl}, "Ctrl+Space", "⌃␣", "^Spc"},
+		{Key{Paste: "hello"}, "Paste", "Paste", "Paste"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := tt.key.Label(LabelText); got != tt.text {
+				t.Errorf("LabelText = %q, want %q", got, tt.text)
+			}
+			if got := tt.key.Label(LabelSymbols); got != tt.symbols {
+				t.Errorf("LabelSymbols = %q, want %q", got, tt.symbols)
+			}
+			if got := tt.key.Label(LabelCompact); got != tt.compact {
+				t.Errorf("LabelCompact = %q, want %q", got, tt.compact)
+			}
+		})
+	}
+}
+
+func TestLabelPatterns(t *testing.T) {
+	tests := []struct {
+		pattern                string
+		text, symbols, compact string
+	}{
+		{"gg", "g g", "G G", "gg"},
+		{"<C-w>j", "Ctrl+W j", "⌃W J", "^Wj"},
+		{"<C-x><C-s>", "Ctrl+X Ctrl+S", "⌃X ⌃S", "^X^S"},
+	}
+	for _, tt := range tests {
+		if got := LabelText.Pattern(tt.pattern); got != tt.text {
+			t.Errorf("LabelText.Pattern(%q) = %q, want %q", tt.pattern, got, tt.text)
+		}
+		if got := LabelSymbols.Pattern(tt.pattern); got != tt.symbols {
+			t.Errorf("LabelSymbols.Pattern(%q) = %q, want %q", tt.pattern, got, tt.symbols)
+		}
+		if got := LabelCompact.Pattern(tt.pattern); got != tt.compact {
+			t.Errorf("LabelCompact.Pattern(%q) = %q, want %q", tt.pattern, got, tt.compact)
+		}
+	}
+}
+
+func TestBindingsInLabelStyle(t *testing.T) {
+	r := NewRouter().SetAlias("Leader", "<Space>")
+	r.HandleNamed("save", "<C-s>", func(m Match) {})
+	r.HandleNamed("find", "<Leader>f", func(m Match) {})
+	r.Rebind("save", "<C-x><C-s>")
+
+	want := []Binding{
+		{Name: "save", Pattern: "⌃X ⌃S", DefaultPattern: "⌃S"},
+		{Name: "find", Pattern: "␣ F", DefaultPattern: "␣ F"},
+	}
+	if got := r.BindingsIn(LabelSymbols); !reflect.DeepEqual(got, want) {
+		t.Errorf("BindingsIn(LabelSymbols) = %+v, want %+v", got, want)
+	}
+}
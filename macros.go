@@ -0,0 +1,205 @@
+package riffkey
+
+import "maps"
+
+// defaultMacroDepth limits nested macro playback unless SetMacroDepth
+// changes it.
+const defaultMacroDepth = 100
+
+// String returns the macro in vim pattern notation, e.g. "2dd<Esc>".
+func (m Macro) String() string {
+	return FormatPattern(m)
+}
+
+// MarshalText encodes the macro in vim pattern notation, so registers can be
+// stored in text formats. Macro has no MarshalJSON of its own: JSON uses
+// this too, encoding a macro as a string.
+func (m Macro) MarshalText() ([]byte, error) {
+	return []byte(FormatPattern(m)), nil
+}
+
+// UnmarshalText decodes a macro written in vim pattern notation.
+func (m *Macro) UnmarshalText(text []byte) error {
+	*m = ParsePattern(string(text))
+	return nil
+}
+
+// StartRecordingRegister begins recording keys, as StartRecording does,
+// into the named register: StopRecording saves the macro there.
+//
+// Example, vim's q{register} and @{register}:
+//
+//	for _, c := range "abcdefghijklmnopqrstuvwxyz" {
+//	    reg := string(c)
+//	    router.Handle("q"+reg, func(m riffkey.Match) { input.StartRecordingRegister(reg) })
+//	    router.Handle("@"+reg, func(m riffkey.Match) { input.ExecuteRegister(reg, m.Count) })
+//	}
+//	router.Handle("@@", func(m riffkey.Match) { input.ExecuteRegister("@", m.Count) })
+func (i *Input) StartRecordingRegister(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.startRecording(name)
+}
+
+// RecordingRegister returns the register being recorded into, or "" when
+// not recording into one.
+func (i *Input) RecordingRegister() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if !i.recording {
+		return ""
+	}
+	return i.recordRegister
+}
+
+// Register returns the macro in the named register, or nil. The name "@"
+// refers to the register last executed.
+func (i *Input) Register(name string) Macro {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if name == "@" {
+		name = i.lastRegister
+	}
+	return i.registers[name]
+}
+
+// SetRegister stores macro in the named register. An empty macro clears it.
+func (i *Input) SetRegister(name string, macro Macro) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.setRegister(name, macro)
+}
+
+func (i *Input) setRegister(name string, macro Macro) {
+	if len(macro) == 0 {
+		delete(i.registers, name)
+		return
+	}
+	if i.registers == nil {
+		i.registers = make(map[string]Macro)
+	}
+	i.registers[name] = macro
+}
+
+// Registers returns a copy of the non-empty registers, for saving across
+// sessions. It encodes to JSON as an object of pattern strings.
+//
+// Example:
+//
+//	data, _ := json.Marshal(input.Registers()) // "a" maps to "2dd<Esc>"
+func (i *Input) Registers() map[string]Macro {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	regs := make(map[string]Macro, len(i.registers))
+	maps.Copy(regs, i.registers)
+	return regs
+}
+
+// SetRegisters replaces every register with regs, for restoring registers
+// saved with Registers.
+func (i *Input) SetRegisters(regs map[string]Macro) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.registers = nil
+	for name, m := range regs {
+		i.setRegister(name, m)
+	}
+}
+
+// ExecuteRegister plays the macro in the named register count times, as
+// ExecuteMacro does (3@a plays register a three times), and remembers the
+// register for "@": ExecuteRegister("@", n) plays the last executed register
+// again, like vim's @@. It reports false if the register is empty or the
+// playback would nest deeper than SetMacroDepth allows.
+func (i *Input) ExecuteRegister(name string, count int) bool {
+	i.mu.Lock()
+	if name == "@" {
+		name = i.lastRegister
+	}
+	macro := i.registers[name]
+	if len(macro) > 0 {
+		i.lastRegister = name
+	}
+	i.mu.Unlock()
+
+	if len(macro) == 0 {
+		return false
+	}
+	return i.execute(macro, max(count, 1))
+}
+
+// SetMacroDepth limits how deeply macro playback may nest, through handlers
+// that play macros, before further playback is skipped. The default is 100;
+// n <= 0 restores it.
+func (i *Input) SetMacroDepth(n int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.macroDepth = n
+}
+
+// SetRecordMatches chooses what recording captures. By default it is the
+// raw keys as dispatched. With on, it is each match as resolved, its count
+// followed by its keys, and of keys that matched nothing, the one passed to
+// HandleUnmatched. Broken sequences, keys still pending when recording
+// stops, and the timing that resolved an ambiguous match are left out.
+func (i *Input) SetRecordMatches(on bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.recordMatches = on
+}
+
+// recordResolved notes where the keys of a resolved match, or of keys that
+// matched nothing, start in the recording, for StopRecording: typed is how
+// many of the last keys dispatched belong to it or came after it. When
+// recording matches it records count and keys instead. Caller must hold
+// i.mu.
+func (i *Input) recordResolved(typed int, count string, keys []Key) {
+	if !i.recording {
+		return
+	}
+	if i.executing > 0 || i.repeating {
+		i.lastStart = len(i.macroBuffer) // not recorded
+		return
+	}
+	if !i.recordMatches {
+		i.lastStart = max(len(i.macroBuffer)-typed, 0)
+		return
+	}
+	i.lastStart = len(i.macroBuffer)
+	for _, r := range count {
+		i.macroBuffer = append(i.macroBuffer, Key{Rune: r})
+	}
+	i.macroBuffer = append(i.macroBuffer, keys...)
+}
+
+// triggered wraps h, fired by keys recorded from start, so StopRecording
+// called from it knows where its trigger starts.
+func (i *Input) triggered(start int, h Handler) Handler {
+	return func(m Match) {
+		i.mu.Lock()
+		was, prev := i.inTrigger, i.triggerStart
+		i.inTrigger, i.triggerStart = true, start
+		i.mu.Unlock()
+		defer func() {
+			i.mu.Lock()
+			i.inTrigger, i.triggerStart = was, prev
+			i.mu.Unlock()
+		}()
+		h(m)
+	}
+}
+
+// trigger returns where the keys that stop the recording start. Caller must
+// hold i.mu.
+func (i *Input) trigger() int {
+	if i.inTrigger {
+		return i.triggerStart
+	}
+	if len(i.buffer) > 0 || i.countBuffer != "" {
+		if i.recordMatches {
+			return len(i.macroBuffer) // pending keys aren't recorded
+		}
+		return max(len(i.macroBuffer)-len(i.buffer)-len(i.countBuffer), 0)
+	}
+	return i.lastStart
+}
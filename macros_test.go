@@ -0,0 +1,246 @@
+package riffkey
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMacroText(t *testing.T) {
+	macro := Macro{{Rune: '2'}, {Rune: 'd'}, {Rune: 'd'}, {Rune: '<'}, {Special: SpecialEscape}, {Rune: 'w', Mod: ModCtrl}, {Special: SpecialSpace}}
+	text, err := macro.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "2dd<lt><Esc><C-w><Space>" || macro.String() != string(text) {
+		t.Errorf("MarshalText = %q", text)
+	}
+	var back Macro
+	if err := back.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, macro) {
+		t.Errorf("round trip = %v, want %v", back, macro)
+	}
+
+	data, err := json.Marshal(map[string]Macro{"a": macro})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs map[string]string
+	if err := json.Unmarshal(data, &strs); err != nil || strs["a"] != string(text) {
+		t.Errorf("JSON = %s, want the macro as a pattern string", data)
+	}
+	var regs map[string]Macro
+	if err := json.Unmarshal(data, &regs); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(regs["a"], macro) {
+		t.Errorf("JSON round trip = %v", regs["a"])
+	}
+}
+
+func TestMacroRegisters(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	var log string
+	r.Handle("x", func(m Match) { log += "x" })
+	r.Handle("y", func(m Match) { log += "y" })
+	r.Handle("!", func(m Match) { in.StopRecording() })
+	r.Handle("qa", func(m Match) { in.StartRecordingRegister("a") })
+	r.Handle("@a", func(m Match) { in.ExecuteRegister("a", m.Count) })
+	r.Handle("@@", func(m Match) { in.ExecuteRegister("@", m.Count) })
+
+	for _, k := range "qa" {
+		in.Dispatch(Key{Rune: k})
+	}
+	if in.RecordingRegister() != "a" {
+		t.Errorf("RecordingRegister() = %q, want a", in.RecordingRegister())
+	}
+	for _, k := range "xy!" {
+		in.Dispatch(Key{Rune: k})
+	}
+	if got := in.Register("a").String(); got != "xy" {
+		t.Fatalf("register a = %q, want xy", got)
+	}
+	if in.RecordingRegister() != "" {
+		t.Error("still recording after StopRecording")
+	}
+
+	log = ""
+	for _, k := range "3@a" {
+		in.Dispatch(Key{Rune: k})
+	}
+	if log != "xyxyxy" {
+		t.Errorf("3@a ran %q", log)
+	}
+	log = ""
+	for _, k := range "@@" {
+		in.Dispatch(Key{Rune: k})
+	}
+	if log != "xy" || in.Register("@").String() != "xy" {
+		t.Errorf("@@ ran %q", log)
+	}
+	if in.ExecuteRegister("z", 1) {
+		t.Error("executing an empty register should report false")
+	}
+
+	saved := in.Registers()
+	in.SetRegisters(nil)
+	if in.Register("a") != nil {
+		t.Error("SetRegisters(nil) should clear the registers")
+	}
+	in.SetRegisters(saved)
+	if in.Register("a").String() != "xy" {
+		t.Error("registers not restored")
+	}
+}
+
+func TestMacroDepthLimit(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	runs := 0
+	r.Handle("x", func(m Match) { runs++ })
+	r.Handle("@a", func(m Match) { in.ExecuteRegister("a", 1) })
+	in.SetRegister("a", Macro{{Rune: 'x'}, {Rune: '@'}, {Rune: 'a'}}) // runs itself
+	in.SetMacroDepth(5)
+
+	in.ExecuteRegister("a", 1)
+	if runs != 5 {
+		t.Errorf("recursive macro ran x %d times, want 5", runs)
+	}
+}
+
+func TestMacroDepthLimitWithExecutor(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	q := NewQueue()
+	in.SetExecutor(q.Submit)
+	runs := 0
+	r.Handle("x", func(m Match) { runs++ })
+	r.Handle("@a", func(m Match) { in.ExecuteRegister("a", 1) })
+	in.SetRegister("a", Macro{{Rune: 'x'}, {Rune: '@'}, {Rune: 'a'}})
+	in.SetMacroDepth(5)
+
+	in.ExecuteRegister("a", 1)
+	done := make(chan struct{})
+	go func() {
+		q.Drain()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain didn't return; the depth limit was ignored")
+	}
+	if runs != 5 {
+		t.Errorf("recursive macro ran x %d times, want 5", runs)
+	}
+}
+
+func TestNestedMacroDoesNotResumeRecording(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	r.Handle("x", func(m Match) {})
+	r.Handle("@", func(m Match) { in.ExecuteMacro(Macro{{Rune: 'x'}}) })
+
+	in.StartRecording()
+	in.Dispatch(Key{Rune: 'x'})
+	in.ExecuteMacro(Macro{{Rune: '@'}, {Rune: 'x'}})
+	in.Dispatch(Key{Rune: 'q'})
+	if got := in.StopRecording().String(); got != "x" {
+		t.Errorf("recorded %q, want x", got)
+	}
+}
+
+func TestRecordingExcludesTriggers(t *testing.T) {
+	r := NewRouter().SetAlias("Leader", "<Space>")
+	in := NewInput(r)
+	r.Handle("x", func(m Match) {})
+	r.Handle("dw", func(m Match) {})
+	r.Handle("<Leader>r", func(m Match) { in.StartRecording() })
+	r.Handle("<Leader>q", func(m Match) { in.StopRecording() })
+	r.Handle("<Leader>s", func(m Match) { in.StartRecordingRegister("s") })
+
+	keys := func(s string) {
+		for _, k := range ParsePattern(s) {
+			in.Dispatch(k)
+		}
+	}
+	keys("<Space>r3dwx2<Space>q")
+	keys("<Space>sx<Space>q")
+	if got := in.Register("s").String(); got != "x" {
+		t.Errorf("register s = %q, want x", got)
+	}
+
+	// stopped outside a handler: pending keys are dropped
+	keys("<Space>r2dwx3d")
+	if got := in.StopRecording().String(); got != "2dwx" {
+		t.Errorf("recorded %q, want 2dwx", got)
+	}
+}
+
+func TestRecordingTriggerWithCount(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	var macro Macro
+	r.Handle("j", func(m Match) {})
+	r.Handle("qq", func(m Match) { in.StartRecording() })
+	r.Handle("Q", func(m Match) { macro = in.StopRecording() })
+
+	for _, k := range "qq2j12Q" {
+		in.Dispatch(Key{Rune: k})
+	}
+	if got := macro.String(); got != "2j" {
+		t.Errorf("recorded %q, want 2j", got)
+	}
+}
+
+func TestRecordMatches(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	var typed []rune
+	r.Handle("gg", func(m Match) {})
+	r.Handle("dd", func(m Match) {})
+	r.Handle("q", func(m Match) { in.StopRecording() })
+	r.HandleUnmatched(func(k Key) bool {
+		typed = append(typed, k.Rune)
+		return true
+	})
+	in.SetRecordMatches(true)
+
+	in.StartRecordingRegister("m")
+	for _, k := range "3ddgxa2q" { // "gx" matches nothing; only x reaches HandleUnmatched
+		in.Dispatch(Key{Rune: k})
+	}
+	if got := in.Register("m").String(); got != "3ddxa" {
+		t.Errorf("recorded %q, want 3ddxa", got)
+	}
+	if string(typed) != "xa" {
+		t.Errorf("unmatched keys = %q", string(typed))
+	}
+}
+
+func TestRecordingRegisterResetsTrigger(t *testing.T) {
+	r := NewRouter()
+	in := NewInput(r)
+	r.Handle("x", func(m Match) {})
+	r.Handle("dd", func(m Match) {})
+
+	in.StartRecording()
+	for _, k := range "xxxx" {
+		in.Dispatch(Key{Rune: k})
+	}
+	in.StopRecording()
+
+	// nothing resolves in this recording, so nothing of it is the trigger
+	// but the pending "d" that Clear discards
+	in.StartRecordingRegister("a")
+	in.Dispatch(Key{Rune: 'd'})
+	in.Clear()
+	in.StopRecording()
+	if got := in.Register("a").String(); got != "" {
+		t.Errorf("register a = %q, want it empty", got)
+	}
+}
@@ -0,0 +1,56 @@
+package riffkey
+
+// Resolved is a dispatch outcome sent on the channel returned by
+// Input.Matches: either a match or keys that matched nothing.
+type Resolved struct {
+	Binding   string  // name of the matched binding, if it is named
+	Match     Match   // keys and count of the match; zero for unmatched keys
+	Router    *Router // frame router that matched; nil for unmatched keys
+	Unmatched []Key   // keys that matched nothing; nil for a match
+}
+
+// Matches switches the Input to channel mode and returns the channel. From
+// then on, resolved matches are sent on the channel instead of running
+// their handlers, hooks and middleware, and keys that match nothing are
+// sent instead of going to HandleUnmatched fallbacks, so a plain Go event
+// loop can select on input alongside timers, network events and signals.
+// Matching works as before: sequences, counts, mounts and the ambiguity timeout all apply, and
+// Subscribe still reports each step. The channel is never closed.
+//
+// Items are sent in dispatch order, without Input's lock held. When the
+// channel's buffer is full, the send blocks the goroutine that dispatched
+// the key (Run's, or the timeout's), which stops Run reading input until
+// the receiver catches up; keys dispatched on other goroutines meanwhile
+// queue behind it rather than block. A goroutine that both receives and
+// dispatches, for example through ExecuteMacro, can block on its own send
+// if the buffer is full, so give the channel room for what it dispatches.
+//
+// Example:
+//
+//	matches := input.Matches(16)
+//	go input.Run(reader, nil)
+//	for {
+//	    select {
+//	    case m := <-matches:
+//	        switch {
+//	        case m.Unmatched != nil:
+//	            insertText(m.Unmatched)
+//	        case m.Binding == "quit":
+//	            return
+//	        }
+//	    case <-ticker.C:
+//	        redraw()
+//	    }
+//	}
+func (i *Input) Matches(buffer int) <-chan Resolved {
+	ch := make(chan Resolved, buffer)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.matches = ch
+	return ch
+}
+
+// resolve queues r to be sent on the match channel. Caller must hold i.mu.
+func (i *Input) resolve(r Resolved) {
+	i.resolved = append(i.resolved, r)
+}
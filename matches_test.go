@@ -0,0 +1,99 @@
+package riffkey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchesChannel(t *testing.T) {
+	called := false
+	r := NewRouter().Name("normal").Timeout(10 * time.Millisecond)
+	r.HandleNamed("down", "j", func(m Match) { called = true })
+	r.HandleNamed("top", "gg", func(m Match) { called = true })
+	r.HandleNamed("delete", "d", func(m Match) { called = true })
+	r.Handle("dd", func(m Match) { called = true })
+	r.HandleUnmatched(func(Key) bool { called = true; return true })
+	r.AddOnBefore(func() { called = true })
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) { called = true })
+	r.Mount("<Space>g", git)
+	in := NewInput(r)
+	matches := in.Matches(8)
+
+	next := func() Resolved {
+		t.Helper()
+		select {
+		case m := <-matches:
+			return m
+		case <-time.After(time.Second):
+			t.Fatal("no match on the channel")
+			return Resolved{}
+		}
+	}
+
+	in.Dispatch(Key{Rune: '3'})
+	in.Dispatch(Key{Rune: 'j'})
+	if m := next(); m.Binding != "down" || m.Match.Count != 3 || m.Router != r || m.Unmatched != nil {
+		t.Errorf("3j = %+v", m)
+	}
+
+	in.Dispatch(Key{Special: SpecialSpace})
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 's'})
+	if m := next(); m.Binding != "git.status" || FormatPattern(m.Match.Keys) != "<Space>gs" {
+		t.Errorf("<Space>gs = %+v", m)
+	}
+
+	in.Dispatch(Key{Rune: 'd'}) // ambiguous with dd; fires on the timeout
+	if m := next(); m.Binding != "delete" || m.Match.Count != 1 {
+		t.Errorf("timed-out d = %+v", m)
+	}
+
+	in.Dispatch(Key{Rune: 'd'})
+	in.Flush()
+	if m := next(); m.Binding != "delete" {
+		t.Errorf("flushed d = %+v", m)
+	}
+
+	if in.Dispatch(Key{Rune: 'z'}) {
+		t.Error("an unmatched key should not be reported handled in channel mode")
+	}
+	if m := next(); FormatPattern(m.Unmatched) != "z" || m.Router != nil || m.Binding != "" {
+		t.Errorf("unmatched z = %+v", m)
+	}
+
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 'x'})
+	if m := next(); FormatPattern(m.Unmatched) != "gx" {
+		t.Errorf("unmatched gx = %+v", m)
+	}
+
+	if called {
+		t.Error("handlers, hooks or fallbacks ran in channel mode")
+	}
+}
+
+func TestMatchesChannelBackpressure(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("down", "j", func(m Match) {})
+	in := NewInput(r)
+	matches := in.Matches(0)
+
+	done := make(chan struct{})
+	go func() {
+		in.Dispatch(Key{Rune: 'j'})
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Dispatch returned before its match was received")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if in.Depth() != 1 {
+		t.Error("Input should stay usable while a send blocks")
+	}
+	if m := <-matches; m.Binding != "down" {
+		t.Errorf("got %+v", m)
+	}
+	<-done
+}
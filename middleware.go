@@ -0,0 +1,66 @@
+package riffkey
+
+// Middleware wraps handler invocations. It is called with the Match, the
+// name of the binding (empty for Handle patterns, namespaced for mounted
+// bindings), the frame router that matched, and next, which runs the rest
+// of the chain, the hooks and the handler. Middleware can inspect the Match
+// or pass a modified one to next, do work around next, or return without
+// calling next to skip the handler and its hooks.
+//
+// Middleware applies wherever a handler fires: on a key, on the ambiguity
+// timeout, through Flush and during ExecuteMacro. It is not called for
+// HandleUnmatched fallbacks, nor in channel mode (see Input.Matches).
+type Middleware func(m Match, binding string, r *Router, next Handler)
+
+// Use adds middleware around the router's handlers, including those reached
+// through its mounts and those it inherits. Middleware runs in the order
+// added, inside any added with Input.Use and, for a mounted binding, outside
+// the mounted router's own.
+//
+// Example, a read-only mode:
+//
+//	router.Use(func(m riffkey.Match, binding string, r *riffkey.Router, next riffkey.Handler) {
+//	    if readOnly && strings.HasPrefix(binding, "edit.") {
+//	        return
+//	    }
+//	    next(m)
+//	})
+func (r *Router) Use(mw ...Middleware) *Router {
+	r.middleware = append(r.middleware, mw...)
+	return r
+}
+
+// Use adds middleware around every handler the Input fires, whichever
+// router it belongs to. Middleware runs in the order added, outside the
+// routers' own.
+//
+// Example, timing handlers:
+//
+//	input.Use(func(m riffkey.Match, binding string, r *riffkey.Router, next riffkey.Handler) {
+//	    start := time.Now()
+//	    next(m)
+//	    log.Printf("%s took %v", binding, time.Since(start))
+//	})
+func (i *Input) Use(mw ...Middleware) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.middleware = append(i.middleware, mw...)
+}
+
+// wrap returns a Handler that runs h with its hooks inside the middleware
+// of the Input, the frame router and, for a mounted binding, the router
+// that owns it. Caller must hold i.mu.
+func (i *Input) wrap(h Handler, name string, matched, owner *Router) Handler {
+	chain := append([]Middleware(nil), i.middleware...)
+	chain = append(chain, matched.middleware...)
+	if owner != nil && owner != matched {
+		chain = append(chain, owner.middleware...)
+	}
+
+	run := func(m Match) { runHandler(h, m, matched, owner) }
+	for k := len(chain) - 1; k >= 0; k-- {
+		mw, next := chain[k], run
+		run = func(m Match) { mw(m, name, matched, next) }
+	}
+	return run
+}
@@ -0,0 +1,111 @@
+package riffkey
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrderAndSkip(t *testing.T) {
+	var log []string
+	logger := func(tag string) Middleware {
+		return func(m Match, binding string, r *Router, next Handler) {
+			log = append(log, tag+" "+binding+" "+r.GetName())
+			next(m)
+		}
+	}
+
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) { log = append(log, "status") })
+	git.Use(logger("git"))
+
+	r := NewRouter().Name("normal")
+	r.HandleNamed("down", "j", func(m Match) { log = append(log, "down") })
+	r.HandleNamed("delete", "x", func(m Match) { log = append(log, "delete") })
+	r.Mount("g", git)
+	r.AddOnBefore(func() { log = append(log, "before") })
+	r.Use(logger("router"))
+	r.Use(func(m Match, binding string, r *Router, next Handler) {
+		if binding == "delete" {
+			log = append(log, "read-only")
+			return
+		}
+		next(m)
+	})
+
+	in := NewInput(r)
+	in.Use(logger("input"))
+
+	in.Dispatch(Key{Rune: 'j'})
+	want := []string{"input down normal", "router down normal", "before", "down"}
+	if !slices.Equal(log, want) {
+		t.Errorf("j: %v, want %v", log, want)
+	}
+
+	log = nil
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 's'})
+	want = []string{"input git.status normal", "router git.status normal", "git git.status normal", "before", "status"}
+	if !slices.Equal(log, want) {
+		t.Errorf("gs: %v, want %v", log, want)
+	}
+
+	log = nil
+	in.Dispatch(Key{Rune: 'x'})
+	want = []string{"input delete normal", "router delete normal", "read-only"}
+	if !slices.Equal(log, want) {
+		t.Errorf("x: %v, want %v", log, want)
+	}
+}
+
+func TestMiddlewareModifiesMatch(t *testing.T) {
+	r := NewRouter()
+	var got int
+	r.Handle("j", func(m Match) { got = m.Count })
+	r.Use(func(m Match, binding string, r *Router, next Handler) {
+		m.Count = min(m.Count, 5)
+		next(m)
+	})
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: '9'})
+	in.Dispatch(Key{Rune: 'j'})
+	if got != 5 {
+		t.Errorf("count = %d, want 5", got)
+	}
+}
+
+func TestMiddlewareWrapsEveryDispatchPath(t *testing.T) {
+	var wrapped, hooks []string
+	r := NewRouter().Timeout(10 * time.Millisecond)
+	done := make(chan struct{}, 4)
+	r.HandleNamed("delete", "d", func(m Match) {})
+	r.Handle("dd", func(m Match) {})
+	r.HandleNamed("down", "j", func(m Match) {})
+	r.AddOnAfter(func() { hooks = append(hooks, "after") })
+	r.AddOnAfter(func() { done <- struct{}{} })
+	in := NewInput(r)
+	in.Use(func(m Match, binding string, r *Router, next Handler) {
+		wrapped = append(wrapped, binding)
+		next(m)
+	})
+
+	in.Dispatch(Key{Rune: 'd'}) // timeout
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout handler never ran")
+	}
+
+	in.Dispatch(Key{Rune: 'd'})
+	in.Flush()
+	<-done
+
+	in.ExecuteMacro(Macro{{Rune: 'j'}})
+
+	if !slices.Equal(wrapped, []string{"delete", "delete", "down"}) {
+		t.Errorf("middleware saw %v", wrapped)
+	}
+	if len(hooks) != 3 {
+		t.Errorf("after hooks ran %d times, want 3 (Flush included)", len(hooks))
+	}
+}
@@ -0,0 +1,128 @@
+package riffkey
+
+// AddMode registers r as the router for the mode called name, so it can be
+// pushed or switched to by name and is reported under that name by Mode,
+// Modes and EventMode. Registering a name again replaces its router.
+//
+// Routers that aren't registered are reported by their own name (see
+// Router.Name), so pushing a router named "insert" enters mode "insert"
+// either way.
+//
+// Example:
+//
+//	input.AddMode("normal", normal)
+//	input.AddMode("insert", insert)
+//	input.OnModeChange(func(prev, mode string) {
+//	    setCursorShape(mode == "insert")
+//	})
+func (i *Input) AddMode(name string, r *Router) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.modes == nil {
+		i.modes = make(map[string]*Router)
+	}
+	i.modes[name] = r
+}
+
+// PushMode pushes the router registered for the mode, as Push does, and
+// reports whether the mode is registered.
+func (i *Input) PushMode(name string) bool {
+	r := i.modeRouter(name)
+	if r == nil {
+		return false
+	}
+	i.Push(r)
+	return true
+}
+
+// SwitchMode replaces the active frame with the router registered for the
+// mode, dropping the sub-routers attached to it, and reports whether the
+// mode is registered. Frames below it are kept, so a later Pop returns to
+// the mode beneath as usual. With a single frame it switches the base mode.
+func (i *Input) SwitchMode(name string) bool {
+	r := i.modeRouter(name)
+	if r == nil {
+		return false
+	}
+	i.mu.Lock()
+	defer i.deliver()
+	defer i.mu.Unlock()
+	prev := i.mode()
+	i.clearBuffer()
+	if len(i.stack) == 0 {
+		i.stack = []*frame{{primary: r}}
+	} else {
+		i.stack[len(i.stack)-1] = &frame{primary: r}
+	}
+	i.modeChanged(prev)
+	return true
+}
+
+// Mode returns the name of the current mode: the mode the active frame's
+// router is registered under, or else the router's own name. It is empty
+// for an empty stack or an unnamed router.
+func (i *Input) Mode() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.mode()
+}
+
+// Modes returns the names of the modes on the stack, from the base frame to
+// the active one.
+func (i *Input) Modes() []string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	names := make([]string, len(i.stack))
+	for n, f := range i.stack {
+		names[n] = i.modeName(f.primary)
+	}
+	return names
+}
+
+// OnModeChange registers fn to be called with the previous and the new mode
+// whenever the current mode changes, through Push, Pop, SetRouter or the
+// mode methods. It is a shorthand for Subscribe filtered to EventMode, and
+// returns a function that removes it.
+func (i *Input) OnModeChange(fn func(prev, mode string)) (unsubscribe func()) {
+	return i.Subscribe(func(e Event) {
+		if e.Kind == EventMode {
+			fn(e.PrevMode, e.Mode)
+		}
+	})
+}
+
+func (i *Input) modeRouter(name string) *Router {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.modes[name]
+}
+
+// mode returns the current mode name. Caller must hold i.mu.
+func (i *Input) mode() string {
+	if len(i.stack) == 0 {
+		return ""
+	}
+	return i.modeName(i.stack[len(i.stack)-1].primary)
+}
+
+// modeName returns the mode r is registered under, or r's name. Caller must
+// hold i.mu.
+func (i *Input) modeName(r *Router) string {
+	for name, m := range i.modes {
+		if m == r {
+			return name
+		}
+	}
+	if r == nil {
+		return ""
+	}
+	return r.name
+}
+
+// modeChanged emits EventMode if the current mode is no longer prev.
+// Caller must hold i.mu.
+func (i *Input) modeChanged(prev string) {
+	if mode := i.mode(); mode != prev {
+		i.emit(Event{Kind: EventMode, Mode: mode, PrevMode: prev})
+	}
+}
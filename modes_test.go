@@ -0,0 +1,81 @@
+package riffkey
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNamedModes(t *testing.T) {
+	normal := NewRouter()
+	insert := NewRouter()
+	visual := NewRouter()
+	search := NewRouter().Name("search")
+	in := NewInput(normal)
+	in.AddMode("normal", normal)
+	in.AddMode("insert", insert)
+	in.AddMode("visual", visual)
+
+	var changes []string
+	unsubscribe := in.OnModeChange(func(prev, mode string) {
+		changes = append(changes, prev+">"+mode)
+	})
+
+	if in.Mode() != "normal" {
+		t.Errorf("Mode() = %q, want normal", in.Mode())
+	}
+	if !in.PushMode("insert") {
+		t.Fatal("PushMode(insert) = false")
+	}
+	if in.PushMode("replace") {
+		t.Error("PushMode of an unregistered mode should fail")
+	}
+	in.SwitchMode("visual")
+	in.Push(search) // unregistered, known by its router name
+	if got := in.Modes(); !slices.Equal(got, []string{"normal", "visual", "search"}) {
+		t.Errorf("Modes() = %v", got)
+	}
+	in.Pop()
+	in.Pop()
+	in.Pop() // the base frame stays; no change
+	in.Push(normal)
+	in.SetRouter(insert) // base changes, but the active mode doesn't
+
+	want := []string{"normal>insert", "insert>visual", "visual>search", "search>visual", "visual>normal"}
+	if !slices.Equal(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+	if got := in.Modes(); !slices.Equal(got, []string{"insert", "normal"}) {
+		t.Errorf("Modes() = %v", got)
+	}
+
+	unsubscribe()
+	in.Pop()
+	if len(changes) != len(want) {
+		t.Error("OnModeChange called after unsubscribing")
+	}
+}
+
+func TestModeChangeFromHandler(t *testing.T) {
+	normal := NewRouter()
+	insert := NewRouter()
+	in := NewInput(normal)
+	in.AddMode("normal", normal)
+	in.AddMode("insert", insert)
+	normal.Handle("i", func(m Match) { in.PushMode("insert") })
+	insert.Handle("<Esc>", func(m Match) { in.Pop() })
+
+	var got []Event
+	in.Subscribe(func(e Event) { got = append(got, e) })
+	in.Dispatch(Key{Rune: 'i'})
+	in.Dispatch(Key{Special: SpecialEscape})
+
+	var modes []string
+	for _, e := range got {
+		if e.Kind == EventMode {
+			modes = append(modes, e.Mode)
+		}
+	}
+	if !slices.Equal(modes, []string{"insert", "normal"}) {
+		t.Errorf("mode events = %v", modes)
+	}
+}
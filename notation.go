@@ -0,0 +1,356 @@
+package riffkey
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Notation selects the syntax used to write key patterns.
+type Notation uint8
+
+const (
+	NotationVim    Notation = iota // "<C-x><C-s>", the default
+	NotationEmacs                  // "C-x C-s"
+	NotationVSCode                 // "ctrl+x ctrl+s"
+)
+
+// String returns the notation's name as used in config files.
+func (n Notation) String() string {
+	switch n {
+	case NotationEmacs:
+		return "emacs"
+	case NotationVSCode:
+		return "vscode"
+	default:
+		return "vim"
+	}
+}
+
+// ParseNotation returns the notation with the given name ("vim", "emacs" or
+// "vscode", case-insensitive).
+func ParseNotation(name string) (Notation, bool) {
+	switch strings.ToLower(name) {
+	case "vim":
+		return NotationVim, true
+	case "emacs":
+		return NotationEmacs, true
+	case "vscode", "vs code", "code":
+		return NotationVSCode, true
+	}
+	return NotationVim, false
+}
+
+// Parse parses a pattern written in the notation.
+func (n Notation) Parse(pattern string) []Key {
+	switch n {
+	case NotationEmacs:
+		return ParseEmacs(pattern)
+	case NotationVSCode:
+		return ParseVSCode(pattern)
+	default:
+		return ParsePattern(pattern)
+	}
+}
+
+// Format writes keys in the notation.
+func (n Notation) Format(keys []Key) string {
+	switch n {
+	case NotationEmacs:
+		return FormatEmacs(keys)
+	case NotationVSCode:
+		return FormatVSCode(keys)
+	default:
+		return FormatPattern(keys)
+	}
+}
+
+// join writes pattern b after pattern a. Emacs and VS Code separate the
+// chords of a sequence with spaces; vim runs them together.
+func (n Notation) join(a, b string) string {
+	if n == NotationVim || a == "" || b == "" {
+		return a + b
+	}
+	return a + " " + b
+}
+
+var specialToEmacs = map[Special]string{
+	SpecialEscape:    "ESC",
+	SpecialEnter:     "RET",
+	SpecialTab:       "TAB",
+	SpecialSpace:     "SPC",
+	SpecialBackspace: "DEL",
+	SpecialUp:        "<up>",
+	SpecialDown:      "<down>",
+	SpecialLeft:      "<left>",
+	SpecialRight:     "<right>",
+	SpecialHome:      "<home>",
+	SpecialEnd:       "<end>",
+	SpecialPageUp:    "<prior>",
+	SpecialPageDown:  "<next>",
+	SpecialInsert:    "<insert>",
+	SpecialDelete:    "<delete>",
+	SpecialF1:        "<f1>",
+	SpecialF2:        "<f2>",
+	SpecialF3:        "<f3>",
+	SpecialF4:        "<f4>",
+	SpecialF5:        "<f5>",
+	SpecialF6:        "<f6>",
+	SpecialF7:        "<f7>",
+	SpecialF8:        "<f8>",
+	SpecialF9:        "<f9>",
+	SpecialF10:       "<f10>",
+	SpecialF11:       "<f11>",
+	SpecialF12:       "<f12>",
+}
+
+var emacsToSpecial = map[string]Special{
+	"ESC":         SpecialEscape,
+	"RET":         SpecialEnter,
+	"TAB":         SpecialTab,
+	"SPC":         SpecialSpace,
+	"DEL":         SpecialBackspace,
+	"<escape>":    SpecialEscape,
+	"<return>":    SpecialEnter,
+	"<tab>":       SpecialTab,
+	"<backspace>": SpecialBackspace,
+	"<up>":        SpecialUp,
+	"<down>":      SpecialDown,
+	"<left>":      SpecialLeft,
+	"<right>":     SpecialRight,
+	"<home>":      SpecialHome,
+	"<end>":       SpecialEnd,
+	"<prior>":     SpecialPageUp,
+	"<next>":      SpecialPageDown,
+	"<insert>":    SpecialInsert,
+	"<delete>":    SpecialDelete,
+	"<f1>":        SpecialF1,
+	"<f2>":        SpecialF2,
+	"<f3>":        SpecialF3,
+	"<f4>":        SpecialF4,
+	"<f5>":        SpecialF5,
+	"<f6>":        SpecialF6,
+	"<f7>":        SpecialF7,
+	"<f8>":        SpecialF8,
+	"<f9>":        SpecialF9,
+	"<f10>":       SpecialF10,
+	"<f11>":       SpecialF11,
+	"<f12>":       SpecialF12,
+}
+
+// ParseEmacs parses a pattern in Emacs kbd notation into the same Keys
+// ParsePattern produces. Chords are separated by spaces and use the C-
+// (Ctrl), M- or A- (Alt), S- (Shift) and s- (Super) prefixes; special keys
+// are RET, TAB, SPC, ESC, DEL (Backspace) and bracketed names like <up>,
+// <prior> (PageUp) or <f1>. An unmodified word that isn't a key name is a
+// sequence of characters, as in kbd: "C-x 4 f" and "C-x 4f" are equivalent.
+func ParseEmacs(pattern string) []Key {
+	var keys []Key
+	for _, chord := range strings.Fields(pattern) {
+		var mod Modifier
+		for len(chord) > 2 && chord[1] == '-' && strings.IndexByte("CMASs", chord[0]) != -1 {
+			switch chord[0] {
+			case 'C':
+				mod |= ModCtrl
+			case 'M', 'A':
+				mod |= ModAlt
+			case 'S':
+				mod |= ModShift
+			case 's':
+				mod |= ModSuper
+			}
+			chord = chord[2:]
+		}
+
+		if special, ok := emacsToSpecial[chord]; ok {
+			keys = append(keys, Key{Special: special, Mod: mod})
+			continue
+		}
+		if special, ok := emacsToSpecial[strings.ToLower(chord)]; ok && strings.HasPrefix(chord, "<") {
+			keys = append(keys, Key{Special: special, Mod: mod})
+			continue
+		}
+		// The modifiers apply to the first character; any others are literal
+		for i, r := range chord {
+			if i == 0 {
+				keys = append(keys, Key{Rune: r, Mod: mod})
+			} else {
+				keys = append(keys, Key{Rune: r})
+			}
+		}
+	}
+	return keys
+}
+
+// FormatEmacs writes keys in Emacs kbd notation, one space-separated chord
+// per key. Paste events and empty keys are omitted.
+func FormatEmacs(keys []Key) string {
+	chords := make([]string, 0, len(keys))
+	for _, k := range keys {
+		var name string
+		switch {
+		case k.IsPaste():
+			continue
+		case k.Special != SpecialNone:
+			name = specialToEmacs[k.Special]
+		case k.Rune == ' ':
+			name = "SPC"
+		case k.Rune != 0:
+			name = string(k.Rune)
+		default:
+			continue
+		}
+
+		var sb strings.Builder
+		if k.Mod&ModCtrl != 0 {
+			sb.WriteString("C-")
+		}
+		if k.Mod&ModAlt != 0 {
+			sb.WriteString("M-")
+		}
+		if k.Mod&ModShift != 0 {
+			sb.WriteString("S-")
+		}
+		if k.Mod&ModSuper != 0 {
+			sb.WriteString("s-")
+		}
+		sb.WriteString(name)
+		chords = append(chords, sb.String())
+	}
+	return strings.Join(chords, " ")
+}
+
+var specialToVSCode = map[Special]string{
+	SpecialEscape:    "escape",
+	SpecialEnter:     "enter",
+	SpecialTab:       "tab",
+	SpecialSpace:     "space",
+	SpecialBackspace: "backspace",
+	SpecialUp:        "up",
+	SpecialDown:      "down",
+	SpecialLeft:      "left",
+	SpecialRight:     "right",
+	SpecialHome:      "home",
+	SpecialEnd:       "end",
+	SpecialPageUp:    "pageup",
+	SpecialPageDown:  "pagedown",
+	SpecialInsert:    "insert",
+	SpecialDelete:    "delete",
+	SpecialF1:        "f1",
+	SpecialF2:        "f2",
+	SpecialF3:        "f3",
+	SpecialF4:        "f4",
+	SpecialF5:        "f5",
+	SpecialF6:        "f6",
+	SpecialF7:        "f7",
+	SpecialF8:        "f8",
+	SpecialF9:        "f9",
+	SpecialF10:       "f10",
+	SpecialF11:       "f11",
+	SpecialF12:       "f12",
+}
+
+var vscodeToSpecial = map[string]Special{
+	"esc":    SpecialEscape,
+	"return": SpecialEnter,
+}
+
+func init() {
+	for s, name := range specialToVSCode {
+		vscodeToSpecial[name] = s
+	}
+}
+
+// ParseVSCode parses a pattern in VS Code keybinding notation into the same
+// Keys ParsePattern produces. Chords are separated by spaces and join
+// modifiers (ctrl, shift, alt, meta/cmd/win) and a key with '+', as in
+// "ctrl+k ctrl+s". Key names are case-insensitive; "shift+a" is Key{'a',
+// ModShift}, which NormalizeKey folds into 'A'.
+func ParseVSCode(pattern string) []Key {
+	var keys []Key
+	for _, chord := range strings.Fields(pattern) {
+		var mods []string
+		base := chord
+		switch {
+		case chord == "+":
+		case strings.HasSuffix(chord, "++"):
+			mods = strings.Split(chord[:len(chord)-2], "+")
+			base = "+"
+		default:
+			if i := strings.LastIndexByte(chord, '+'); i != -1 {
+				mods = strings.Split(chord[:i], "+")
+				base = chord[i+1:]
+			}
+		}
+
+		var mod Modifier
+		for _, m := range mods {
+			switch strings.ToLower(m) {
+			case "ctrl", "control":
+				mod |= ModCtrl
+			case "alt", "option", "opt":
+				mod |= ModAlt
+			case "shift":
+				mod |= ModShift
+			case "meta", "cmd", "win", "super":
+				mod |= ModSuper
+			}
+		}
+
+		if special, ok := vscodeToSpecial[strings.ToLower(base)]; ok {
+			keys = append(keys, Key{Special: special, Mod: mod})
+			continue
+		}
+		if r, size := utf8.DecodeRuneInString(base); size == len(base) && size > 0 {
+			keys = append(keys, Key{Rune: r, Mod: mod})
+			continue
+		}
+		for _, r := range base {
+			keys = append(keys, Key{Rune: r, Mod: mod})
+		}
+	}
+	return keys
+}
+
+// FormatVSCode writes keys in VS Code keybinding notation. Upper-case letters
+// are written as shift+letter, as VS Code does. Paste events and empty keys
+// are omitted.
+func FormatVSCode(keys []Key) string {
+	chords := make([]string, 0, len(keys))
+	for _, k := range keys {
+		mod := k.Mod
+		var name string
+		switch {
+		case k.IsPaste():
+			continue
+		case k.Special != SpecialNone:
+			name = specialToVSCode[k.Special]
+		case k.Rune == ' ':
+			name = "space"
+		case unicode.IsUpper(k.Rune):
+			name = string(unicode.ToLower(k.Rune))
+			mod |= ModShift
+		case k.Rune != 0:
+			name = string(k.Rune)
+		default:
+			continue
+		}
+
+		var sb strings.Builder
+		if mod&ModCtrl != 0 {
+			sb.WriteString("ctrl+")
+		}
+		if mod&ModShift != 0 {
+			sb.WriteString("shift+")
+		}
+		if mod&ModAlt != 0 {
+			sb.WriteString("alt+")
+		}
+		if mod&ModSuper != 0 {
+			sb.WriteString("meta+")
+		}
+		sb.WriteString(name)
+		chords = append(chords, sb.String())
+	}
+	return strings.Join(chords, " ")
+}
@@ -0,0 +1,251 @@
+package riffkey
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseEmacs(t *testing.T) {
+	tests := []struct {
+		pattern string
+		vim     string // equivalent vim pattern
+	}{
+		{"C-x C-s", "<C-x><C-s>"},
+		{"C-x 4 f", "<C-x>4f"},
+		{"C-x 4f", "<C-x>4f"},
+		{"M-x", "<A-x>"},
+		{"A-x", "<A-x>"},
+		{"C-M-d", "<C-A-d>"},
+		{"s-s", "<D-s>"},
+		{"S-<up>", "<S-Up>"},
+		{"C-<f1>", "<C-F1>"},
+		{"RET", "<CR>"},
+		{"TAB SPC ESC DEL", "<Tab><Space><Esc><BS>"},
+		{"<prior> <next>", "<PageUp><PageDown>"},
+		{"<home> <end> <insert> <delete>", "<Home><End><Insert><Del>"},
+		{"g g", "gg"},
+		{"C--", "<C-->"},
+		{"<", "<lt>"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got := ParseEmacs(tt.pattern)
+			if want := ParsePattern(tt.vim); !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseEmacs(%q) = %v, want %v", tt.pattern, got, want)
+			}
+		})
+	}
+}
+
+func TestParseVSCode(t *testing.T) {
+	tests := []struct {
+		pattern string
+		vim     string
+	}{
+		{"ctrl+k ctrl+s", "<C-k><C-s>"},
+		{"ctrl+shift+p", "<C-S-p>"},
+		{"Ctrl+Shift+P", "<C-S-P>"},
+		{"alt+x", "<A-x>"},
+		{"cmd+s", "<D-s>"},
+		{"meta+s", "<D-s>"},
+		{"shift+tab", "<S-Tab>"},
+		{"escape", "<Esc>"},
+		{"enter", "<CR>"},
+		{"ctrl+space", "<C-Space>"},
+		{"pageup pagedown", "<PageUp><PageDown>"},
+		{"f5", "<F5>"},
+		{"ctrl++", "<C-+>"},
+		{"+", "+"},
+		{"g g", "gg"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got := ParseVSCode(tt.pattern)
+			if want := ParsePattern(tt.vim); !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseVSCode(%q) = %v, want %v", tt.pattern, got, want)
+			}
+		})
+	}
+}
+
+func TestFormatNotations(t *testing.T) {
+	keys := ParsePattern("<C-x><C-s>G<S-Up><PageDown><A-x>")
+	if got, want := FormatEmacs(keys), "C-x C-s G S-<up> <next> M-x"; got != want {
+		t.Errorf("FormatEmacs = %q, want %q", got, want)
+	}
+	if got, want := FormatVSCode(keys), "ctrl+x ctrl+s shift+g shift+up pagedown alt+x"; got != want {
+		t.Errorf("FormatVSCode = %q, want %q", got, want)
+	}
+}
+
+func TestNotationRoundTrip(t *testing.T) {
+	var keys []Key
+	for _, mod := range []Modifier{ModNone, ModCtrl, ModAlt, ModCtrl | ModAlt, ModSuper} {
+		for s := SpecialEscape; s <= SpecialF12; s++ {
+			keys = append(keys, Key{Special: s, Mod: mod})
+		}
+		for r := rune('!'); r <= '~'; r++ {
+			keys = append(keys, NormalizeKey(Key{Rune: r, Mod: mod}))
+		}
+	}
+
+	for _, n := range []Notation{NotationVim, NotationEmacs, NotationVSCode} {
+		got := n.Parse(n.Format(keys))
+		for i := range got {
+			got[i] = NormalizeKey(got[i])
+		}
+		if !reflect.DeepEqual(got, keys) {
+			t.Errorf("%s: keys did not round-trip", n)
+		}
+	}
+}
+
+func TestParseNotation(t *testing.T) {
+	for name, want := range map[string]Notation{"vim": NotationVim, "Emacs": NotationEmacs, "vscode": NotationVSCode} {
+		if got, ok := ParseNotation(name); !ok || got != want {
+			t.Errorf("ParseNotation(%q) = %v, %v", name, got, ok)
+		}
+		if want.String() != map[Notation]string{NotationVim: "vim", NotationEmacs: "emacs", NotationVSCode: "vscode"}[want] {
+			t.Errorf("unexpected String() %q", want.String())
+		}
+	}
+	if _, ok := ParseNotation("sublime"); ok {
+		t.Error("expected unknown notation to fail")
+	}
+}
+
+func TestRouterNotation(t *testing.T) {
+	r := NewRouter(WithNotation(NotationEmacs))
+	var saves atomic.Int32
+	r.HandleNamed("save", "C-x C-s", func(m Match) { saves.Add(1) })
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'x', Mod: ModCtrl})
+	in.Dispatch(Key{Rune: 's', Mod: ModCtrl})
+	if saves.Load() != 1 {
+		t.Fatalf("expected emacs pattern to match, got %d", saves.Load())
+	}
+
+	r.SetNotation(NotationVSCode)
+	r.Rebind("save", "ctrl+s")
+	in.Dispatch(Key{Rune: 's', Mod: ModCtrl})
+	if saves.Load() != 2 {
+		t.Errorf("expected vscode pattern to match, got %d", saves.Load())
+	}
+}
+
+func TestMountInEmacsNotation(t *testing.T) {
+	r := NewRouter(WithNotation(NotationEmacs))
+	files := NewRouter(WithNotation(NotationEmacs)).Name("files")
+	var saves atomic.Int32
+	files.HandleNamed("save", "C-s", func(m Match) { saves.Add(1) })
+	git := NewRouter().Name("git") // vim notation, converted for r
+	git.HandleNamed("status", "<C-g>s", func(m Match) {})
+	r.Mount("C-x", files)
+	r.Mount("C-c", git)
+
+	want := map[string]string{"files.save": "C-x C-s", "git.status": "C-c C-g s"}
+	if got := r.BindingsMap(); !maps.Equal(got, want) {
+		t.Errorf("BindingsMap() = %v, want %v", got, want)
+	}
+	for _, b := range r.Bindings() {
+		if got := FormatEmacs(ParseEmacs(b.Pattern)); got != b.Pattern {
+			t.Errorf("%s: %q doesn't round-trip (%q)", b.Name, b.Pattern, got)
+		}
+	}
+
+	if !r.Rebind("files.save", "C-x C-w") {
+		t.Fatal("Rebind through an emacs mount failed")
+	}
+	if got := files.BindingsMap()["save"]; got != "C-w" {
+		t.Errorf("files save = %q, want C-w", got)
+	}
+	if r.Rebind("files.save", "C-c C-w") || r.Rebind("files.save", "C-x") {
+		t.Error("Rebind should need the mount prefix and keys after it")
+	}
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'x', Mod: ModCtrl})
+	in.Dispatch(Key{Rune: 'w', Mod: ModCtrl})
+	if saves.Load() != 1 {
+		t.Errorf("rebound save fired %d times, want 1", saves.Load())
+	}
+}
+
+func TestBindingsIn(t *testing.T) {
+	r := NewRouter().SetAlias("Leader", "<Space>")
+	r.HandleNamed("save", "<C-x><C-s>", func(m Match) {})
+	r.HandleNamed("find", "<Leader>f", func(m Match) {})
+
+	want := []Binding{
+		{Name: "save", Pattern: "C-x C-s", DefaultPattern: "C-x C-s"},
+		{Name: "find", Pattern: "SPC f", DefaultPattern: "SPC f"},
+	}
+	if got := r.BindingsIn(NotationEmacs); !reflect.DeepEqual(got, want) {
+		t.Errorf("BindingsIn(emacs) = %+v, want %+v", got, want)
+	}
+
+	want = []Binding{
+		{Name: "save", Pattern: "ctrl+x ctrl+s", DefaultPattern: "ctrl+x ctrl+s"},
+		{Name: "find", Pattern: "space f", DefaultPattern: "space f"},
+	}
+	if got := r.BindingsIn(NotationVSCode); !reflect.DeepEqual(got, want) {
+		t.Errorf("BindingsIn(vscode) = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadBindingsFileNotation(t *testing.T) {
+	r := NewRouter()
+	var saves atomic.Int32
+	r.HandleNamed("save", "<C-s>", func(m Match) { saves.Add(1) })
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	config := "notation = \"vscode\"\n\n[myapp]\nsave = \"ctrl+x ctrl+s\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatalf("LoadBindingsFrom error: %v", err)
+	}
+	if got := r.BindingsMap()["save"]; got != "<C-x><C-s>" {
+		t.Errorf("expected pattern converted to vim notation, got %q", got)
+	}
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'x', Mod: ModCtrl})
+	in.Dispatch(Key{Rune: 's', Mod: ModCtrl})
+	if saves.Load() != 1 {
+		t.Errorf("expected converted binding to fire, got %d", saves.Load())
+	}
+}
+
+func TestLoadBindingsFileNotationReportsInvalidPatterns(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("save", "<C-s>", func(m Match) {})
+	r.HandleNamed("quit", "q", func(m Match) {})
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	config := "notation = \"emacs\"\n\n[myapp]\nsave = \"C-<hoem>\"\nquit = \"C-x C-c\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diags, err := r.LoadBindingsReport(path, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcomes := make(map[string]Outcome)
+	for _, d := range diags {
+		outcomes[d.Name] = d.Outcome
+	}
+	if outcomes["save"] != OutcomeInvalidPattern {
+		t.Errorf("save = %v, want an invalid pattern in emacs notation", outcomes["save"])
+	}
+	if outcomes["quit"] != OutcomeApplied {
+		t.Errorf("quit = %v, want applied", outcomes["quit"])
+	}
+}
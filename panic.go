@@ -0,0 +1,52 @@
+package riffkey
+
+import "runtime/debug"
+
+// Panic describes a panic recovered from a handler, reported to the
+// callback set with Input.OnPanic.
+type Panic struct {
+	Value   any    // the value passed to panic
+	Binding string // name of the binding whose handler panicked, if named
+	Keys    []Key  // keys that fired the handler
+	Stack   []byte // stack trace of the panicking goroutine
+}
+
+// OnPanic makes the Input recover panics in handlers, their hooks and
+// middleware, and HandleUnmatched fallbacks, and report them to fn instead
+// of crashing. This covers handlers fired by the ambiguity timeout, which
+// run on a goroutine of their own where an unrecovered panic ends the
+// process. The key that fired the handler has been consumed by then, so the
+// Input carries on with the next key; Push, Pop and the like made by the
+// handler before it panicked stay in effect. An unmatched fallback that
+// panics counts as not having consumed its key.
+//
+// fn runs on the goroutine of the handler that panicked, without Input's
+// lock held. Pass nil to let panics propagate again.
+//
+// Example:
+//
+//	input.OnPanic(func(p riffkey.Panic) {
+//	    log.Printf("%s (%s) panicked: %v\n%s", p.Binding, riffkey.FormatPattern(p.Keys), p.Value, p.Stack)
+//	})
+func (i *Input) OnPanic(fn func(Panic)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.onPanic = fn
+}
+
+// guard returns fn, recovering and reporting a panic in it if an OnPanic
+// callback is set. Caller must hold i.mu.
+func (i *Input) guard(binding string, keys []Key, fn func()) func() {
+	report := i.onPanic
+	if report == nil {
+		return fn
+	}
+	return func() {
+		defer func() {
+			if v := recover(); v != nil {
+				report(Panic{Value: v, Binding: binding, Keys: keys, Stack: debug.Stack()})
+			}
+		}()
+		fn()
+	}
+}
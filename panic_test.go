@@ -0,0 +1,112 @@
+package riffkey
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOnPanicRecoversHandlers(t *testing.T) {
+	panics := make(chan Panic, 4)
+	var ran []string
+	r := NewRouter().Timeout(10 * time.Millisecond)
+	r.HandleNamed("boom", "b", func(m Match) { panic("boom") })
+	r.HandleNamed("delete", "d", func(m Match) { panic("timeout boom") })
+	r.Handle("dd", func(m Match) {})
+	r.HandleNamed("down", "j", func(m Match) { ran = append(ran, "down") })
+	r.HandleUnmatched(func(k Key) bool { panic("fallback boom") })
+	in := NewInput(r)
+	in.OnPanic(func(p Panic) { panics <- p })
+
+	in.Dispatch(Key{Rune: '2'})
+	if !in.Dispatch(Key{Rune: 'b'}) {
+		t.Error("a key whose handler panicked is still handled")
+	}
+	p := <-panics
+	if p.Value != "boom" || p.Binding != "boom" || FormatPattern(p.Keys) != "b" || len(p.Stack) == 0 {
+		t.Errorf("panic = %+v", p)
+	}
+	if count, keys := in.Pending(); count != "" || len(keys) != 0 {
+		t.Errorf("pending after panic = %q %v", count, keys)
+	}
+
+	in.Dispatch(Key{Rune: 'd'}) // fires on the timeout goroutine
+	select {
+	case p := <-panics:
+		if p.Value != "timeout boom" || p.Binding != "delete" {
+			t.Errorf("timeout panic = %+v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout panic not reported")
+	}
+
+	if in.Dispatch(Key{Rune: 'z'}) {
+		t.Error("a fallback that panicked should not consume its key")
+	}
+	if p := <-panics; p.Value != "fallback boom" || p.Binding != "" || FormatPattern(p.Keys) != "z" {
+		t.Errorf("fallback panic = %+v", p)
+	}
+
+	in.Dispatch(Key{Rune: 'j'})
+	if !slices.Equal(ran, []string{"down"}) {
+		t.Errorf("handlers after panics: %v", ran)
+	}
+}
+
+func TestPanicLeavesInputUsable(t *testing.T) {
+	r := NewRouter()
+	r.Handle("b", func(m Match) { panic("boom") })
+	var ran int
+	r.Handle("j", func(m Match) { ran++ })
+	in := NewInput(r)
+	var events int
+	in.Subscribe(func(e Event) {
+		if e.Kind == EventHandler {
+			events++
+		}
+	})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("without OnPanic the panic should propagate")
+			}
+		}()
+		in.ExecuteMacro(Macro{{Rune: 'j'}, {Rune: 'b'}, {Rune: 'j'}})
+	}()
+
+	in.StartRecording()
+	in.Dispatch(Key{Rune: 'j'})
+	in.Dispatch(Key{Rune: 'q'})
+	if macro := in.StopRecording(); FormatPattern(macro) != "j" {
+		t.Errorf("recorded %q after a panicking macro, want j", FormatPattern(macro))
+	}
+	if ran != 2 || events != 3 {
+		t.Errorf("ran %d handlers and saw %d handler events, want 2 and 3", ran, events)
+	}
+}
+
+func TestPanickingSubscriberDoesNotStallEvents(t *testing.T) {
+	r := NewRouter()
+	r.Handle("j", func(m Match) {})
+	in := NewInput(r)
+	var seen int
+	once := true
+	in.Subscribe(func(e Event) {
+		seen++
+		if once {
+			once = false
+			panic("subscriber boom")
+		}
+	})
+
+	func() {
+		defer func() { recover() }()
+		in.Dispatch(Key{Rune: 'j'})
+	}()
+	seen = 0
+	in.Dispatch(Key{Rune: 'j'})
+	if seen == 0 {
+		t.Error("events stopped after a subscriber panicked")
+	}
+}
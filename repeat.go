@@ -0,0 +1,75 @@
+package riffkey
+
+// change is the last repeatable match, for Input.Repeat.
+type change struct {
+	hit
+	m       Match
+	matched *Router
+	typed   []Key // keys dispatched in frames pushed by the change
+}
+
+// captureChange adds key to the change in progress if the change pushed a
+// frame that is still on the stack, and otherwise ends the capture. Caller
+// must hold i.mu.
+func (i *Input) captureChange(key Key) {
+	if !i.capturing || i.repeating {
+		return
+	}
+	if len(i.stack) > i.changeDepth {
+		i.lastChange.typed = append(i.lastChange.typed, key)
+	} else {
+		i.capturing = false
+	}
+}
+
+// Repeat repeats the last change, like vim's ".": the handler of the last
+// binding marked with Router.Repeatable runs again with the same Match,
+// with count in place of the original count if count > 0. If the handler
+// pushed a frame, such as insert mode, the keys typed there until it was
+// popped are dispatched again too, so "cw" followed by a word and <Esc>
+// repeats the whole edit. Repeatable bindings fired while a change's frame
+// is open are part of that change.
+//
+// The repeat is not a new change, and its keys are not recorded into a
+// macro. The handler runs on the calling goroutine with its hooks and
+// middleware, even with an executor set. Repeat reports whether there was
+// a change to repeat; it does nothing when called from a repeat.
+//
+// Example:
+//
+//	router.HandleNamed("delete_line", "dd", deleteLine)
+//	router.Repeatable("delete_line")
+//	router.HandleNamed("repeat", ".", func(m riffkey.Match) {
+//	    count := 0
+//	    if m.HasCount {
+//	        count = m.Count // 3. repeats with count 3
+//	    }
+//	    input.Repeat(count)
+//	})
+func (i *Input) Repeat(count int) bool {
+	i.mu.Lock()
+	c := i.lastChange
+	if c == nil || i.repeating {
+		i.mu.Unlock()
+		return false
+	}
+	i.repeating = true
+	m := c.m
+	if count > 0 {
+		m.Count, m.HasCount = count, true
+	}
+	h := i.wrap(c.handler, c.name, c.matched, c.owner)
+	run := i.guard(c.name, m.Keys, func() { h(m) })
+	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		i.repeating = false
+		i.mu.Unlock()
+	}()
+
+	run()
+	for _, k := range c.typed {
+		i.Dispatch(k)
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package riffkey
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRepeatLastChange(t *testing.T) {
+	var log []string
+	normal := NewRouter()
+	in := NewInput(normal)
+	normal.HandleNamed("delete_line", "dd", func(m Match) {
+		log = append(log, "dd "+FormatPattern(m.Keys)+" "+string(rune('0'+m.Count)))
+	})
+	normal.HandleNamed("down", "j", func(m Match) { log = append(log, "j") })
+	normal.HandleNamed("repeat", ".", func(m Match) {
+		count := 0
+		if m.HasCount {
+			count = m.Count
+		}
+		in.Repeat(count)
+	})
+	if in.Repeat(0) {
+		t.Error("Repeat with no change should report false")
+	}
+	if !normal.Repeatable("delete_line") || normal.Repeatable("missing") {
+		t.Fatal("Repeatable should report whether the binding exists")
+	}
+
+	for _, k := range "2ddj." {
+		in.Dispatch(Key{Rune: k})
+	}
+	in.Dispatch(Key{Rune: '3'})
+	in.Dispatch(Key{Rune: '.'})
+	in.Dispatch(Key{Rune: '.'}) // the count of the last repeat isn't kept
+	want := []string{"dd dd 2", "j", "dd dd 2", "dd dd 3", "dd dd 2"}
+	if !slices.Equal(log, want) {
+		t.Errorf("log = %v, want %v", log, want)
+	}
+
+	for _, b := range normal.Bindings() {
+		if b.Repeatable != (b.Name == "delete_line") {
+			t.Errorf("%s: Repeatable = %v", b.Name, b.Repeatable)
+		}
+	}
+}
+
+func TestRepeatReplaysInsertedKeys(t *testing.T) {
+	var text []rune
+	normal := NewRouter()
+	insert := NewRouter()
+	in := NewInput(normal)
+	normal.HandleNamed("change_word", "cw", func(m Match) {
+		text = append(text, '|')
+		in.Push(insert)
+	})
+	normal.Repeatable("change_word")
+	normal.HandleNamed("repeat", ".", func(m Match) { in.Repeat(0) })
+	insert.Handle("<Esc>", func(m Match) { in.Pop() })
+	insert.HandleUnmatched(func(k Key) bool {
+		text = append(text, k.Rune)
+		return true
+	})
+
+	in.StartRecording()
+	for _, k := range []Key{{Rune: 'c'}, {Rune: 'w'}, {Rune: 'a'}, {Rune: 'b'}, {Special: SpecialEscape}, {Rune: '.'}, {Rune: '.'}} {
+		in.Dispatch(k)
+	}
+	in.Dispatch(Key{Rune: 'q'})
+	macro := in.StopRecording()
+
+	if string(text) != "|ab|ab|ab" {
+		t.Errorf("text = %q, want |ab|ab|ab", string(text))
+	}
+	if in.Depth() != 1 {
+		t.Errorf("depth = %d after repeats, want 1", in.Depth())
+	}
+	if got := FormatPattern(macro); got != "cwab<Esc>.." {
+		t.Errorf("macro = %q; replayed keys should not be recorded", got)
+	}
+}
+
+func TestRepeatableThroughMount(t *testing.T) {
+	var ran int
+	git := NewRouter().Name("git")
+	git.HandleNamed("stage", "a", func(m Match) { ran++ })
+	r := NewRouter()
+	r.Mount("g", git)
+	if !r.Repeatable("git.stage") {
+		t.Fatal("Repeatable(git.stage) = false")
+	}
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 'a'})
+	in.Repeat(0)
+	if ran != 2 {
+		t.Errorf("ran %d times, want 2", ran)
+	}
+}
@@ -1,7 +1,9 @@
 package riffkey
 
 import (
+	"cmp"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -10,12 +12,11 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode"
 	"unicode/utf8"
-
-	"github.com/BurntSushi/toml"
 )
 
-// Modifier represents key modifiers (Ctrl, Alt, Shift).
+// Modifier represents key modifiers (Ctrl, Alt, Shift, Super).
 type Modifier uint8
 
 const (
@@ -23,6 +24,7 @@ const (
 	ModCtrl Modifier = 1 << iota
 	ModAlt
 	ModShift
+	ModSuper // Super/Cmd/Win; only reported by terminals with extended key encodings
 )
 
 // String returns a human-readable representation of the modifier(s).
@@ -40,6 +42,9 @@ func (m Modifier) String() string {
 	if m&ModShift != 0 {
 		parts = append(parts, "Shift")
 	}
+	if m&ModSuper != 0 {
+		parts = append(parts, "Super")
+	}
 	return strings.Join(parts, "+")
 }
 
@@ -114,6 +119,75 @@ func (k Key) IsPaste() bool {
 	return k.Paste != ""
 }
 
+// NormalizeKey returns the canonical form of k. Patterns and dispatched keys
+// are both normalised, so every spelling of a key matches what the Reader
+// emits:
+//   - a space is <Space>, with or without modifiers (" ", "<Space>" and the
+//     Reader's Ctrl+Space all take the same form)
+//   - Ctrl+letter is case-folded to lower case ("<C-W>" is Ctrl+w), since
+//     terminals send the same byte for both
+//   - Ctrl+h, Ctrl+i, Ctrl+m and Ctrl+[ are <BS>, <Tab>, <CR> and <Esc>,
+//     which terminals send the same bytes for, so "<C-h>" matches Backspace
+//     and can't be bound apart from it
+//   - Shift+letter is folded into the shifted letter ("<S-a>" is "A")
+//
+// Alt+letter keeps its case: terminals send ESC followed by the actual
+// character, so "<A-x>" and "<A-X>" are different keys.
+func NormalizeKey(k Key) Key {
+	if k.Special == SpecialNone && k.Rune == ' ' {
+		k.Special, k.Rune = SpecialSpace, 0
+	}
+	if k.Special != SpecialNone || k.Rune == 0 {
+		return k
+	}
+	switch {
+	case k.Mod&ModCtrl != 0:
+		k.Rune = unicode.ToLower(k.Rune)
+		if special, ok := ctrlSpecials[k.Rune]; ok {
+			k.Special, k.Rune = special, 0
+			k.Mod &^= ModCtrl
+		}
+	case k.Mod&ModShift != 0 && unicode.IsLetter(k.Rune):
+		k.Rune = unicode.ToUpper(k.Rune)
+		k.Mod &^= ModShift
+	}
+	return k
+}
+
+// ctrlSpecials maps the Ctrl keys that share their byte with a special key
+// to that key.
+var ctrlSpecials = map[rune]Special{
+	'h': SpecialBackspace,
+	'i': SpecialTab,
+	'm': SpecialEnter,
+	'[': SpecialEscape,
+}
+
+// Layout maps unshifted characters to the characters Shift produces on a
+// keyboard layout. It extends NormalizeKey to fold Shift+symbol into the
+// shifted symbol, e.g. "<S-1>" into "!" on a US keyboard.
+type Layout map[rune]rune
+
+// LayoutUS is the Shift mapping of a US ANSI keyboard.
+var LayoutUS = Layout{
+	'`': '~', '1': '!', '2': '@', '3': '#', '4': '$', '5': '%', '6': '^',
+	'7': '&', '8': '*', '9': '(', '0': ')', '-': '_', '=': '+',
+	'[': '{', ']': '}', '\\': '|', ';': ':', '\'': '"', ',': '<', '.': '>', '/': '?',
+}
+
+// Normalize applies NormalizeKey and then folds Shift+symbol using the
+// layout. A nil Layout only applies NormalizeKey.
+func (l Layout) Normalize(k Key) Key {
+	k = NormalizeKey(k)
+	if k.Mod&ModShift != 0 && k.Mod&ModCtrl == 0 && k.Special == SpecialNone {
+		if shifted, ok := l[k.Rune]; ok {
+			k.Rune = shifted
+			k.Mod &^= ModShift
+		}
+	}
+	return k
+}
+
 // String returns a vim-style representation of the key.
 func (k Key) String() string {
 	if k.Special == SpecialNone && k.Mod == ModNone && k.Rune != 0 {
@@ -130,6 +204,9 @@ func (k Key) String() string {
 	if k.Mod&ModShift != 0 {
 		parts = append(parts, "S")
 	}
+	if k.Mod&ModSuper != 0 {
+		parts = append(parts, "D")
+	}
 
 	var keyPart string
 	if k.Special != SpecialNone {
@@ -211,8 +288,9 @@ var vimToSpecial = map[string]Special{
 
 // Match contains information about a matched key sequence.
 type Match struct {
-	Keys  []Key // The matched key sequence (without count prefix digits)
-	Count int   // Count prefix (defaults to 1 if not specified)
+	Keys     []Key // The matched key sequence (without count prefix digits)
+	Count    int   // Count prefix (defaults to 1 if not specified)
+	HasCount bool  // true if a count prefix was typed
 }
 
 // Handler is a function that handles a matched key sequence.
@@ -227,6 +305,15 @@ type Binding struct {
 	Name           string // Semantic action name (e.g., "scroll_down")
 	Pattern        string // Current pattern (after rebinding)
 	DefaultPattern string // Original default pattern
+	Source         string // Config file that set Pattern; empty if set in code
+	Description    string // Set with Describe; shown in help screens and the config schema
+	Repeatable     bool   // Set with Repeatable; Input.Repeat can repeat it
+}
+
+// Unbound reports whether the binding has been unbound: it has no keys until
+// rebound or reset.
+func (b Binding) Unbound() bool {
+	return b.Pattern == ""
 }
 
 // namedBinding stores internal binding info.
@@ -234,23 +321,44 @@ type namedBinding struct {
 	defaultPattern string
 	currentPattern string
 	handler        Handler
+	seq            uint64 // registration order, for rebuilding the trie
+	source         string // config file that set currentPattern, if any
+	description    string
+	repeatable     bool
+}
+
+// handleEntry records an anonymous Handle registration with its unexpanded
+// pattern, so the trie can be rebuilt when aliases change.
+type handleEntry struct {
+	pattern string
+	handler Handler
+	seq     uint64
 }
 
 // Router matches key patterns to handlers.
+//
+// Changing bindings (Handle, HandleNamed, Rebind, Unbind, Reset, SetAlias,
+// Mount, LoadBindings and friends) is safe while an Input dispatches keys on
+// another goroutine: each change is published as a whole, so a key is
+// matched against either the old bindings or the new ones. A config load is
+// one change per router: LoadBindings applies the whole config to a fork and
+// swaps it in. Settings such as
+// Timeout, NoCounts, HandleUnmatched and the hooks are meant to be set up
+// before dispatching starts.
 type Router struct {
-	root               *trieNode
-	timeout            time.Duration
-	name               string
-	hasEscapeSequences bool              // true if any registered pattern uses keys that generate escape sequences
-	aliases            map[string]string // user-defined pattern aliases (e.g., "Leader" -> ",")
-	namedBindings      map[string]*namedBinding
-	bindingOrder       []string       // preserve registration order for Bindings()
-	unmatched          func(Key) bool // fallback for unmatched keys
-	noCounts           bool           // if true, digits are not treated as count prefixes
+	*bindingTable
+	timeout   time.Duration
+	name      string
+	parent    *Router        // inherited router consulted for keys r doesn't define
+	unmatched func(Key) bool // fallback for unmatched keys
+	noCounts  bool           // if true, digits are not treated as count prefixes
+	layout    Layout         // keyboard layout rules applied on top of NormalizeKey
+	notation  Notation       // syntax of patterns passed to Handle, Rebind etc.
 
 	// Hooks - callbacks that run before/after each matched handler
 	beforeHooks []func()
 	afterHooks  []func()
+	middleware  []Middleware
 
 	// disabled skips this router during frame matching when true.
 	// Zero value = enabled. Safe for concurrent access with Dispatch.
@@ -261,9 +369,234 @@ type Router struct {
 	Send func(any)
 }
 
+// bindingTable holds everything a router matches against. Clone shares the
+// table between routers; Fork copies it. The trie is persistent (updates
+// copy the path they touch and never mutate existing nodes), so a copied
+// table shares nodes with the original until either side changes them.
+//
+// Changes hold mu and end by publishing a snapshot, which matching reads
+// without locking.
+type bindingTable struct {
+	mu   sync.Mutex
+	live atomic.Pointer[snapshot]
+
+	root               *trieNode
+	hasEscapeSequences bool              // true if any registered pattern uses keys that generate escape sequences
+	aliases            map[string]string // user-defined pattern aliases (e.g., "Leader" -> ",")
+	namedBindings      map[string]*namedBinding
+	bindingOrder       []string      // preserve registration order for Bindings()
+	handles            []handleEntry // anonymous Handle registrations
+	mounts             []mount       // sub-routers reachable through a key prefix
+	seq                uint64        // last registration sequence number
+
+	// aliasGen counts changes to aliases, so that inheriting routers,
+	// which expand their patterns with their parents' aliases too, know
+	// to rebuild. builtWith is the sum of the parents' aliasGen the trie
+	// was last built with.
+	aliasGen  atomic.Uint64
+	builtWith uint64
+}
+
+// copy returns an independent table that shares trie nodes with t.
+func (t *bindingTable) copy() *bindingTable {
+	c := &bindingTable{
+		root:               t.root,
+		hasEscapeSequences: t.hasEscapeSequences,
+		aliases:            maps.Clone(t.aliases),
+		namedBindings:      make(map[string]*namedBinding, len(t.namedBindings)),
+		bindingOrder:       slices.Clone(t.bindingOrder),
+		handles:            slices.Clone(t.handles),
+		mounts:             slices.Clone(t.mounts),
+		seq:                t.seq,
+		builtWith:          t.builtWith,
+	}
+	c.aliasGen.Store(t.aliasGen.Load())
+	for name, b := range t.namedBindings {
+		nb := *b
+		c.namedBindings[name] = &nb
+	}
+	return c
+}
+
+// snapshot is the part of a binding table that matching reads. It is
+// never changed once published.
+type snapshot struct {
+	root               *trieNode
+	hasEscapeSequences bool
+	mounts             []mount
+	layout             Layout
+	named              map[string]bool // named bindings, which shadow inherited ones
+	repeatable         map[string]bool
+	builtWith          uint64 // parents' aliasGen the trie was built with
+}
+
+// lock starts a change to r's bindings.
+func (r *Router) lock() {
+	r.mu.Lock()
+}
+
+// unlock publishes the bindings changed since lock and ends the change.
+func (r *Router) unlock() {
+	r.publish()
+	r.mu.Unlock()
+}
+
+// publish makes the current bindings visible to matching. Caller must hold
+// r.mu, or own r exclusively.
+func (r *Router) publish() {
+	s := &snapshot{
+		root:               r.root,
+		hasEscapeSequences: r.hasEscapeSequences,
+		mounts:             slices.Clone(r.mounts),
+		layout:             r.layout,
+		builtWith:          r.builtWith,
+	}
+	if r.parent != nil {
+		s.named = make(map[string]bool, len(r.namedBindings))
+		for name := range r.namedBindings {
+			s.named[name] = true
+		}
+	}
+	for name, b := range r.namedBindings {
+		if b.repeatable {
+			if s.repeatable == nil {
+				s.repeatable = make(map[string]bool)
+			}
+			s.repeatable[name] = true
+		}
+	}
+	r.live.Store(s)
+}
+
+// snapshot returns the bindings last published for matching, first
+// rebuilding them if a parent's aliases changed since.
+func (r *Router) snapshot() *snapshot {
+	s := r.live.Load()
+	if s != nil && r.parent != nil && s.builtWith != r.inheritedAliasGen() {
+		r.lock()
+		r.rebuild()
+		r.unlock()
+		s = r.live.Load()
+	}
+	if s != nil {
+		return s
+	}
+	return &snapshot{root: &trieNode{}}
+}
+
+// assign replaces t's bindings with o's.
+func (t *bindingTable) assign(o *bindingTable) {
+	t.root = o.root
+	t.hasEscapeSequences = o.hasEscapeSequences
+	t.aliases = o.aliases
+	t.namedBindings = o.namedBindings
+	t.bindingOrder = o.bindingOrder
+	t.handles = o.handles
+	t.mounts = o.mounts
+	t.seq = o.seq
+	t.aliasGen.Add(1)
+}
+
+// trieNode is immutable once reachable from a bindingTable root.
 type trieNode struct {
 	children map[Key]*trieNode
 	handler  Handler
+	name     string // named binding that registered handler, if any
+}
+
+// with returns a copy of n with h registered at keys, copying only the
+// nodes along the path.
+func (n *trieNode) with(keys []Key, h Handler, name string) *trieNode {
+	c := &trieNode{handler: n.handler, name: n.name}
+	if len(n.children) > 0 || len(keys) > 0 {
+		c.children = maps.Clone(n.children)
+		if c.children == nil {
+			c.children = make(map[Key]*trieNode)
+		}
+	}
+	if len(keys) == 0 {
+		c.handler = h
+		c.name = name
+		return c
+	}
+	child, ok := n.children[keys[0]]
+	if !ok {
+		child = &trieNode{}
+	}
+	c.children[keys[0]] = child.with(keys[1:], h, name)
+	return c
+}
+
+// without returns a copy of n with the handler at keys cleared if owns
+// reports true for its node, or n itself if there is nothing to clear.
+// Branches left with neither a handler nor children are pruned, so a nil
+// result means n itself became empty.
+func (n *trieNode) without(keys []Key, owns func(*trieNode) bool) *trieNode {
+	if len(keys) == 0 {
+		if n.handler == nil || !owns(n) {
+			return n
+		}
+		if len(n.children) == 0 {
+			return nil
+		}
+		return &trieNode{children: n.children}
+	}
+	child, ok := n.children[keys[0]]
+	if !ok {
+		return n
+	}
+	updated := child.without(keys[1:], owns)
+	if updated == child {
+		return n
+	}
+	c := &trieNode{handler: n.handler, name: n.name, children: maps.Clone(n.children)}
+	if updated == nil {
+		delete(c.children, keys[0])
+		if len(c.children) == 0 && c.handler == nil {
+			return nil
+		}
+	} else {
+		c.children[keys[0]] = updated
+	}
+	return c
+}
+
+// lookup returns the node at keys, or nil.
+func (n *trieNode) lookup(keys []Key) *trieNode {
+	for _, k := range keys {
+		child, ok := n.children[k]
+		if !ok {
+			return nil
+		}
+		n = child
+	}
+	return n
+}
+
+// hasEscapeSequences reports whether any key below n generates a terminal
+// escape sequence.
+func (n *trieNode) hasEscapeSequences() bool {
+	for k, child := range n.children {
+		if generatesEscapeSequence(k) || child.hasEscapeSequences() {
+			return true
+		}
+	}
+	return false
+}
+
+// hit describes the handler a match resolved to.
+type hit struct {
+	handler Handler
+	name    string  // binding name, namespaced through mounts; empty for Handle
+	owner   *Router // router whose hooks wrap the handler
+	repeat  bool    // the binding is repeatable
+}
+
+// mount is a sub-router reachable through a key prefix of its parent.
+type mount struct {
+	prefix string // pattern as given to Mount (may contain aliases)
+	keys   []Key  // parsed prefix
+	sub    *Router
 }
 
 // RouterOption configures a Router.
@@ -280,21 +613,40 @@ func WithSender[T any](s interface{ Send(T) }) RouterOption {
 // NewRouter creates a new Router with default settings.
 func NewRouter(opts ...RouterOption) *Router {
 	r := &Router{
-		root:          &trieNode{children: make(map[Key]*trieNode)},
-		timeout:       2 * time.Second,
-		namedBindings: make(map[string]*namedBinding),
+		bindingTable: newBindingTable(),
+		timeout:      2 * time.Second,
 	}
 	for _, opt := range opts {
 		opt(r)
 	}
+	r.publish()
 	return r
 }
 
+func newBindingTable() *bindingTable {
+	return &bindingTable{
+		root:          &trieNode{},
+		namedBindings: make(map[string]*namedBinding),
+	}
+}
+
 // HasEscapeSequences returns true if any registered pattern uses keys
 // that generate terminal escape sequences (arrows, F-keys, etc.).
 // This can be used to optimize input reading by skipping escape timeouts.
 func (r *Router) HasEscapeSequences() bool {
-	return r.hasEscapeSequences
+	s := r.snapshot()
+	if s.hasEscapeSequences {
+		return true
+	}
+	if r.parent != nil && r.parent.HasEscapeSequences() {
+		return true
+	}
+	for _, m := range s.mounts {
+		if slices.ContainsFunc(m.keys, generatesEscapeSequence) || m.sub.HasEscapeSequences() {
+			return true
+		}
+	}
+	return false
 }
 
 // generatesEscapeSequence returns true if the key generates a terminal
@@ -356,23 +708,131 @@ func (r *Router) IsEnabled() bool {
 // Clone creates a shallow copy of the router that shares the same handlers
 // but can have independent hooks. This is useful for creating variants
 // of a router with different before/after behavior.
+//
+// The clone shares its bindings with r: Handle, Rebind or SetAlias on either
+// affects both. Use Fork for an independent copy, or Inherit for a child
+// router layered over r.
 func (r *Router) Clone() *Router {
 	clone := &Router{
-		root:               r.root, // share the trie
-		timeout:            r.timeout,
-		name:               r.name,
-		hasEscapeSequences: r.hasEscapeSequences,
-		aliases:            r.aliases,
-		namedBindings:      r.namedBindings,
-		bindingOrder:       r.bindingOrder,
-		unmatched:          r.unmatched,
-		noCounts:           r.noCounts,
-		Send:               r.Send,
-		// Don't copy hooks - let the clone start fresh
+		bindingTable: r.bindingTable, // share the bindings
+		timeout:      r.timeout,
+		name:         r.name,
+		parent:       r.parent,
+		unmatched:    r.unmatched,
+		noCounts:     r.noCounts,
+		layout:       r.layout,
+		notation:     r.notation,
+		Send:         r.Send,
+		// Don't copy hooks or middleware - let the clone start fresh
 	}
 	return clone
 }
 
+// Fork creates a copy-on-write copy of the router. The fork starts with the
+// same bindings, aliases, mounts, hooks and middleware as r, but from then
+// on the two diverge: Handle, Rebind, SetAlias and friends on one never
+// affect the other. Routers mounted in r are forked along with it, so
+// rebinding "git.status" or calling ResetAll on the fork leaves r's "git"
+// router alone, and later changes to that router don't show through the
+// fork. Trie nodes are shared until either side changes them.
+func (r *Router) Fork() *Router {
+	return r.fork(make(map[*Router]*Router))
+}
+
+// fork forks r together with the routers mounted in it, recursively. seen
+// maps routers already forked to their forks: a router mounted twice is
+// forked once, and a fork whose parent is in seen inherits from the
+// parent's fork.
+func (r *Router) fork(seen map[*Router]*Router) *Router {
+	if f, ok := seen[r]; ok {
+		return f
+	}
+	r.mu.Lock()
+	f := &Router{
+		bindingTable: r.bindingTable.copy(),
+		timeout:      r.timeout,
+		name:         r.name,
+		parent:       r.parent,
+		unmatched:    r.unmatched,
+		noCounts:     r.noCounts,
+		layout:       r.layout,
+		notation:     r.notation,
+		beforeHooks:  slices.Clone(r.beforeHooks),
+		afterHooks:   slices.Clone(r.afterHooks),
+		middleware:   slices.Clone(r.middleware),
+		Send:         r.Send,
+	}
+	r.mu.Unlock()
+	seen[r] = f
+	if p, ok := seen[f.parent]; ok {
+		f.parent = p
+	}
+	for j, m := range f.mounts {
+		f.mounts[j].sub = m.sub.fork(seen)
+	}
+	f.publish()
+	return f
+}
+
+// adopt replaces r's bindings with those of f, a fork of r made by fork, and
+// does the same for each router mounted in r with its fork. r keeps its own
+// mounts, so the original sub-routers stay mounted. done records the routers
+// already adopted.
+func (r *Router) adopt(f *Router, done map[*Router]bool) {
+	if done[r] {
+		return
+	}
+	done[r] = true
+	r.lock()
+	mounts := r.mounts
+	r.assign(f.bindingTable)
+	r.mounts = mounts
+	r.unlock()
+	for j, m := range mounts {
+		if j < len(f.mounts) && f.mounts[j].prefix == m.prefix {
+			m.sub.adopt(f.mounts[j].sub, done)
+		}
+	}
+}
+
+// Inherit creates an empty child router layered over r. Keys the child does
+// not define fall back to r's bindings, live: later changes to r show through
+// the child, while bindings registered on the child never touch r.
+//
+// A named binding on the child overrides the parent's binding of the same
+// name, and rebinding an inherited name on the child creates such an
+// override. Bindings lists the parent's bindings with overrides applied,
+// followed by the child's own. The child starts with r's timeout, count
+// handling, unmatched fallback and Send, and with no hooks or middleware.
+//
+// The child's patterns may use r's aliases: an alias the child doesn't set
+// itself is looked up in r, then in the routers r inherits from, and the
+// child's patterns are expanded again when one of those aliases changes.
+//
+// Example:
+//
+//	visual := normal.Inherit().Name("visual")
+//	visual.HandleNamed("delete", "d", deleteSelection) // overrides normal's "dd"
+func (r *Router) Inherit() *Router {
+	child := &Router{
+		bindingTable: newBindingTable(),
+		timeout:      r.timeout,
+		parent:       r,
+		unmatched:    r.unmatched,
+		noCounts:     r.noCounts,
+		layout:       r.layout,
+		notation:     r.notation,
+		Send:         r.Send,
+	}
+	child.publish()
+	return child
+}
+
+// Parent returns the router r inherits from, or nil.
+func (r *Router) Parent() *Router {
+	return r.parent
+}
+
 // OnBefore returns a clone of the router with a before-handler hook added.
 // The hook runs before each matched handler. Multiple hooks run in order.
 //
@@ -414,6 +874,12 @@ func (r *Router) AddOnAfter(fn func()) *Router {
 // SetAlias defines a pattern alias that expands in Handle patterns.
 // Alias names are case-insensitive and use angle bracket syntax.
 //
+// Aliases are resolved live: patterns are stored unexpanded and every
+// registered binding and mount prefix is re-expanded when an alias changes,
+// so SetAlias (or an [aliases] config section) also affects bindings
+// registered before it. An expansion may reference other aliases; a
+// reference that would loop back is left unexpanded.
+//
 // Example:
 //
 //	router.SetAlias("Leader", ",")
@@ -421,13 +887,109 @@ func (r *Router) AddOnAfter(fn func()) *Router {
 //	router.Handle("<Leader>f", ...)  // expands to ",f"
 //	router.Handle("<Nav>j", ...)     // expands to "<C-w>j"
 func (r *Router) SetAlias(name, expansion string) *Router {
+	r.lock()
+	defer r.unlock()
 	if r.aliases == nil {
 		r.aliases = make(map[string]string)
 	}
-	r.aliases[strings.ToLower(name)] = expansion
+	name = strings.ToLower(name)
+	if old, ok := r.aliases[name]; ok && old == expansion {
+		return r
+	}
+	r.aliases[name] = expansion
+	r.aliasGen.Add(1)
+	r.rebuild()
 	return r
 }
 
+// Alias describes a pattern alias and how it resolves.
+type Alias struct {
+	Name      string   // alias name, lower-cased
+	Expansion string   // expansion as given to SetAlias
+	Resolved  string   // expansion with nested aliases resolved
+	Refs      []string // aliases referenced directly by Expansion
+	Cycle     bool     // true if resolving Expansion leads back to an alias already being expanded
+}
+
+// Aliases returns the alias graph sorted by name, for debugging how
+// patterns expand.
+func (r *Router) Aliases() []Alias {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := slices.Sorted(maps.Keys(r.aliases))
+	aliases := make([]Alias, 0, len(names))
+	for _, name := range names {
+		expansion := r.aliases[name]
+		resolved, cycle := r.expand(expansion, []string{name})
+		a := Alias{Name: name, Expansion: expansion, Resolved: resolved, Cycle: cycle}
+		for _, ref := range aliasRefs(expansion) {
+			if _, ok := r.aliases[ref]; ok && !slices.Contains(a.Refs, ref) {
+				a.Refs = append(a.Refs, ref)
+			}
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases
+}
+
+// aliasRefs returns the lower-cased names of every <...> span in pattern.
+func aliasRefs(pattern string) []string {
+	var refs []string
+	for {
+		start := strings.IndexByte(pattern, '<')
+		if start == -1 {
+			return refs
+		}
+		end := strings.IndexByte(pattern[start:], '>')
+		if end == -1 {
+			return refs
+		}
+		refs = append(refs, strings.ToLower(pattern[start+1:start+end]))
+		pattern = pattern[start+end+1:]
+	}
+}
+
+// nextSeq returns a new registration sequence number.
+func (r *Router) nextSeq() uint64 {
+	r.seq++
+	return r.seq
+}
+
+// rebuild re-registers every binding and mount prefix from its unexpanded
+// pattern, in registration order. Called when aliases change.
+func (r *Router) rebuild() {
+	r.builtWith = r.inheritedAliasGen()
+	type registration struct {
+		seq     uint64
+		pattern string
+		handler Handler
+		name    string
+	}
+	regs := make([]registration, 0, len(r.handles)+len(r.namedBindings))
+	for _, e := range r.handles {
+		regs = append(regs, registration{e.seq, e.pattern, e.handler, ""})
+	}
+	for name, b := range r.namedBindings {
+		regs = append(regs, registration{b.seq, b.currentPattern, b.handler, name})
+	}
+	slices.SortFunc(regs, func(a, b registration) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	root := &trieNode{}
+	for _, reg := range regs {
+		if keys := r.patternKeys(reg.pattern); len(keys) > 0 {
+			root = root.with(keys, reg.handler, reg.name)
+		}
+	}
+	r.root = root
+	r.hasEscapeSequences = root.hasEscapeSequences()
+
+	for i := range r.mounts {
+		r.mounts[i].keys = r.patternKeys(r.mounts[i].prefix)
+	}
+}
+
 // HandleUnmatched sets a fallback handler for keys that don't match any pattern.
 // The handler returns true if it consumed the key, false otherwise.
 // This is useful for text input modes where most keys should be inserted.
@@ -581,13 +1143,29 @@ func (t *TextHandler) HandleKey(k Key) bool {
 	return true
 }
 
-// expandAliases replaces alias references in a pattern with their expansions.
+// expanded is expandAliases for callers that don't hold r.mu.
+func (r *Router) expanded(pattern string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.expandAliases(pattern)
+}
+
+// expandAliases replaces alias references in a pattern with their expansions,
+// resolving aliases nested inside expansions. Caller must hold r.mu.
 func (r *Router) expandAliases(pattern string) string {
-	if r.aliases == nil {
+	if r.aliases == nil && r.parent == nil {
 		return pattern
 	}
+	expanded, _ := r.expand(pattern, nil)
+	return expanded
+}
 
+// expand resolves aliases in pattern. visiting holds the aliases currently
+// being expanded; a reference back to one of them is left as-is and reported
+// as a cycle.
+func (r *Router) expand(pattern string, visiting []string) (string, bool) {
 	var result strings.Builder
+	cycle := false
 	i := 0
 	for i < len(pattern) {
 		if pattern[i] == '<' {
@@ -601,22 +1179,56 @@ func (r *Router) expandAliases(pattern string) string {
 			end += i
 
 			// Extract the name (without < >)
-			name := pattern[i+1 : end]
+			name := strings.ToLower(pattern[i+1 : end])
 
 			// Check if it's an alias (case-insensitive)
-			if expansion, ok := r.aliases[strings.ToLower(name)]; ok {
-				result.WriteString(expansion)
+			if expansion, ok := r.alias(name); ok && !slices.Contains(visiting, name) {
+				nested, c := r.expand(expansion, append(visiting, name))
+				result.WriteString(nested)
+				cycle = cycle || c
+				i = end + 1
 			} else {
-				// Not an alias, keep as-is
-				result.WriteString(pattern[i : end+1])
+				if ok {
+					cycle = true
+				}
+				// Not an alias (or a cyclic reference): keep the '<' and
+				// rescan after it, so "<<Leader>" still expands the alias
+				result.WriteByte(pattern[i])
+				i++
 			}
-			i = end + 1
 		} else {
 			result.WriteByte(pattern[i])
 			i++
 		}
 	}
-	return result.String()
+	return result.String(), cycle
+}
+
+// alias looks up an alias by its lower-cased name in r, then in the routers
+// r inherits from. Caller must hold r.mu.
+func (r *Router) alias(name string) (string, bool) {
+	if expansion, ok := r.aliases[name]; ok {
+		return expansion, true
+	}
+	for p := r.parent; p != nil; p = p.parent {
+		p.mu.Lock()
+		expansion, ok := p.aliases[name]
+		p.mu.Unlock()
+		if ok {
+			return expansion, true
+		}
+	}
+	return "", false
+}
+
+// inheritedAliasGen sums the aliasGen of the routers r inherits from, which
+// grows whenever one of their aliases changes.
+func (r *Router) inheritedAliasGen() uint64 {
+	var gen uint64
+	for p := r.parent; p != nil; p = p.parent {
+		gen += p.aliasGen.Load()
+	}
+	return gen
 }
 
 // Handle registers a handler for the given pattern.
@@ -636,7 +1248,10 @@ func (r *Router) expandAliases(pattern string) string {
 //   - "<F1>"        → F1 key
 //   - "<PageUp>"    → Page Up key
 func (r *Router) Handle(pattern string, h Handler) {
-	r.registerPattern(pattern, h)
+	r.lock()
+	defer r.unlock()
+	r.handles = append(r.handles, handleEntry{pattern: pattern, handler: h, seq: r.nextSeq()})
+	r.registerPattern(pattern, h, "")
 }
 
 // HandleNamed registers a handler with a semantic name for introspection and rebinding.
@@ -644,13 +1259,15 @@ func (r *Router) Handle(pattern string, h Handler) {
 // Users can later rebind this action using Rebind() or config files.
 // If the name already exists, the old binding is replaced.
 func (r *Router) HandleNamed(name, defaultPattern string, h Handler) {
+	r.lock()
+	defer r.unlock()
 	if r.namedBindings == nil {
 		r.namedBindings = make(map[string]*namedBinding)
 	}
 
 	// If name already exists, remove the old pattern from the trie
 	if existing, ok := r.namedBindings[name]; ok {
-		r.removePattern(existing.currentPattern)
+		r.removePattern(existing.currentPattern, name)
 	} else {
 		// Only add to order if this is a new binding
 		r.bindingOrder = append(r.bindingOrder, name)
@@ -660,8 +1277,9 @@ func (r *Router) HandleNamed(name, defaultPattern string, h Handler) {
 		defaultPattern: defaultPattern,
 		currentPattern: defaultPattern,
 		handler:        h,
+		seq:            r.nextSeq(),
 	}
-	r.registerPattern(defaultPattern, h)
+	r.registerPattern(defaultPattern, h, name)
 }
 
 // HandleMsg registers a message-returning handler for the given pattern.
@@ -686,12 +1304,82 @@ func (r *Router) wrapMsgHandler(h MsgHandler) Handler {
 	}
 }
 
-// registerPattern does the actual pattern registration in the trie.
-func (r *Router) registerPattern(pattern string, h Handler) {
-	// Expand any aliases in the pattern
-	pattern = r.expandAliases(pattern)
+// Mount makes sub's bindings reachable through prefix, the way an HTTP mux
+// mounts a sub-handler. The sub-router is consulted live at match time, so
+// later Handle, Rebind or LoadBindings calls on sub show through the mount.
+//
+// Named bindings of sub appear in Bindings namespaced by sub's name, e.g. a
+// sub-router named "git" with a "status" binding is listed as "git.status"
+// and can be rebound through the parent under that name.
+//
+// Example:
+//
+//	git := riffkey.NewRouter().Name("git")
+//	git.HandleNamed("status", "s", showStatus)
+//	router.Mount("<Leader>g", git) // <Leader>gs → git.status
+//
+// Mounting r itself, a Clone of r or a router that inherits from r is
+// ignored: they share r's bindings, so going through the mount would reach
+// r again.
+func (r *Router) Mount(prefix string, sub *Router) *Router {
+	if sub == nil || r.reachedBy(sub) {
+		return r
+	}
+	r.lock()
+	defer r.unlock()
+	r.mounts = append(r.mounts, mount{
+		prefix: prefix,
+		keys:   r.patternKeys(prefix),
+		sub:    sub,
+	})
+	return r
+}
+
+// reachedBy reports whether sub shares r's binding table, or inherits from
+// a router that does. Calls into such a sub-router from a mount would lock
+// r.mu while r holds it.
+func (r *Router) reachedBy(sub *Router) bool {
+	for p := sub; p != nil; p = p.parent {
+		if p.bindingTable == r.bindingTable {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify returns the name a sub-router binding is exposed under by its parent.
+func (m mount) qualify(name string) string {
+	if m.sub.name == "" {
+		return name
+	}
+	return m.sub.name + "." + name
+}
+
+// mountFor finds the mount that owns a namespaced binding name and returns
+// the name local to the mounted router.
+func (r *Router) mountFor(name string) (mount, string, bool) {
+	for _, m := range r.mounts {
+		if m.sub.name != "" && strings.HasPrefix(name, m.sub.name+".") {
+			return m, name[len(m.sub.name)+1:], true
+		}
+	}
+	return mount{}, "", false
+}
+
+// patternKeys expands aliases in pattern and parses it into the normalised
+// keys the trie is keyed by.
+func (r *Router) patternKeys(pattern string) []Key {
+	keys := r.notation.Parse(r.expandAliases(pattern))
+	for i, k := range keys {
+		keys[i] = r.layout.Normalize(k)
+	}
+	return keys
+}
 
-	keys := ParsePattern(pattern)
+// registerPattern does the actual pattern registration in the trie.
+// name is the named binding being registered, or empty for Handle.
+func (r *Router) registerPattern(pattern string, h Handler, name string) {
+	keys := r.patternKeys(pattern)
 	if len(keys) == 0 {
 		return
 	}
@@ -701,112 +1389,357 @@ func (r *Router) registerPattern(pattern string, h Handler) {
 		r.hasEscapeSequences = true
 	}
 
-	node := r.root
-	for _, k := range keys {
-		if node.children == nil {
-			node.children = make(map[Key]*trieNode)
-		}
-		child, exists := node.children[k]
-		if !exists {
-			child = &trieNode{children: make(map[Key]*trieNode)}
-			node.children[k] = child
-		}
-		node = child
-	}
-	node.handler = h
+	r.root = r.root.with(keys, h, name)
 }
 
-// Rebind changes the pattern for a named binding.
+// Rebind changes the pattern for a named binding. An empty pattern unbinds
+// it, as Unbind does.
 // Returns true if the binding was found and rebound.
 func (r *Router) Rebind(name, pattern string) bool {
+	r.lock()
+	defer r.unlock()
+	return r.rebind(name, pattern)
+}
+
+// rebind implements Rebind. Caller must hold r.mu.
+func (r *Router) rebind(name, pattern string) bool {
 	binding, ok := r.namedBindings[name]
 	if !ok {
+		if inherited, ok := r.inheritedBinding(name); ok {
+			// override the parent's binding on this router only
+			r.bindingOrder = append(r.bindingOrder, name)
+			r.namedBindings[name] = &namedBinding{
+				defaultPattern: inherited.defaultPattern,
+				currentPattern: pattern,
+				handler:        inherited.handler,
+				seq:            r.nextSeq(),
+				description:    inherited.description,
+				repeatable:     inherited.repeatable,
+			}
+			r.registerPattern(pattern, inherited.handler, name)
+			return true
+		}
+		if m, local, ok := r.mountFor(name); ok {
+			if pattern == "" {
+				return m.sub.Rebind(local, "")
+			}
+			// the pattern includes the mount prefix; strip it for the sub-router
+			keys := r.patternKeys(pattern)
+			if len(keys) <= len(m.keys) || !slices.Equal(keys[:len(m.keys)], m.keys) {
+				return false
+			}
+			return m.sub.Rebind(local, m.sub.notation.Format(keys[len(m.keys):]))
+		}
 		return false
 	}
 
 	// Remove old pattern from trie
-	r.removePattern(binding.currentPattern)
+	r.removePattern(binding.currentPattern, name)
 
 	// Register new pattern
 	binding.currentPattern = pattern
-	r.registerPattern(pattern, binding.handler)
+	binding.source = ""
+	binding.seq = r.nextSeq()
+	r.registerPattern(pattern, binding.handler, name)
 	return true
 }
 
-// removePattern removes a pattern from the trie.
-func (r *Router) removePattern(pattern string) {
-	pattern = r.expandAliases(pattern)
-	keys := ParsePattern(pattern)
+// Describe sets a one-line, human-readable description of a named binding,
+// reported by Bindings and used in the config schema.
+// Returns true if the binding was found.
+func (r *Router) Describe(name, description string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.namedBindings[name]; ok {
+		b.description = description
+		return true
+	}
+	if m, local, ok := r.mountFor(name); ok {
+		return m.sub.Describe(local, description)
+	}
+	return false
+}
+
+// Repeatable marks a named binding as a change that Input.Repeat can
+// repeat, like vim's ".": typically bindings that edit, such as "dd" or
+// "x", but not motions. Returns true if the binding was found.
+func (r *Router) Repeatable(name string) bool {
+	r.lock()
+	defer r.unlock()
+	if b, ok := r.namedBindings[name]; ok {
+		b.repeatable = true
+		return true
+	}
+	if m, local, ok := r.mountFor(name); ok {
+		return m.sub.Repeatable(local)
+	}
+	return false
+}
+
+// Unbind removes a named binding's keys while keeping the binding, so it is
+// listed by Bindings with an empty Pattern and Reset restores its default.
+// Use it to disable a dangerous default such as "dd".
+// Returns true if the binding was found.
+func (r *Router) Unbind(name string) bool {
+	return r.Rebind(name, "")
+}
+
+// inheritedBinding looks up a named binding along the parent chain and
+// returns a copy of it.
+func (r *Router) inheritedBinding(name string) (namedBinding, bool) {
+	for p := r.parent; p != nil; p = p.parent {
+		p.mu.Lock()
+		b, ok := p.namedBindings[name]
+		var nb namedBinding
+		if ok {
+			nb = *b
+		}
+		p.mu.Unlock()
+		if ok {
+			return nb, true
+		}
+	}
+	return namedBinding{}, false
+}
+
+// removePattern removes the handler registered by the named binding (or by
+// Handle, for an empty name) at pattern, pruning branches it leaves empty.
+// A handler registered over it by someone else is left in place.
+func (r *Router) removePattern(pattern, name string) {
+	r.removeKeys(r.patternKeys(pattern), func(n *trieNode) bool {
+		return n.name == name
+	})
+}
+
+// removeKeys clears the handler at keys when owns accepts its node, prunes
+// empty branches and recomputes hasEscapeSequences.
+func (r *Router) removeKeys(keys []Key, owns func(*trieNode) bool) {
 	if len(keys) == 0 {
 		return
 	}
+	root := r.root.without(keys, owns)
+	if root == r.root {
+		return
+	}
+	if root == nil {
+		root = &trieNode{}
+	}
+	r.root = root
+	r.hasEscapeSequences = root.hasEscapeSequences()
+}
 
-	// Walk to the node and clear its handler
-	node := r.root
-	for _, k := range keys {
-		child, exists := node.children[k]
-		if !exists {
-			return
-		}
-		node = child
+// Unhandle removes the handler registered at pattern and reports whether
+// there was one. Branches of the trie that no longer lead to a handler are
+// pruned, so prefixes of the removed pattern stop waiting for more input.
+// If the handler belonged to a named binding, that binding is removed too.
+func (r *Router) Unhandle(pattern string) bool {
+	r.lock()
+	defer r.unlock()
+	keys := r.patternKeys(pattern)
+	node := r.root.lookup(keys)
+	if len(keys) == 0 || node == nil || node.handler == nil {
+		return false
+	}
+	if node.name != "" {
+		return r.unhandleNamed(node.name)
 	}
-	node.handler = nil
+	r.handles = slices.DeleteFunc(r.handles, func(e handleEntry) bool {
+		return slices.Equal(r.patternKeys(e.pattern), keys)
+	})
+	r.removeKeys(keys, func(*trieNode) bool { return true })
+	return true
+}
 
-	// Note: we don't prune empty branches for simplicity
-	// This could be optimized if memory is a concern
+// UnhandleNamed removes a named binding and its handler, and reports whether
+// the binding existed. Bindings of mounted sub-routers can be removed by
+// their namespaced name.
+func (r *Router) UnhandleNamed(name string) bool {
+	r.lock()
+	defer r.unlock()
+	return r.unhandleNamed(name)
+}
+
+// unhandleNamed implements UnhandleNamed. Caller must hold r.mu.
+func (r *Router) unhandleNamed(name string) bool {
+	binding, ok := r.namedBindings[name]
+	if !ok {
+		if m, local, ok := r.mountFor(name); ok {
+			return m.sub.UnhandleNamed(local)
+		}
+		return false
+	}
+	r.removePattern(binding.currentPattern, name)
+	delete(r.namedBindings, name)
+	r.bindingOrder = slices.DeleteFunc(r.bindingOrder, func(n string) bool {
+		return n == name
+	})
+	return true
 }
 
 // Reset restores a named binding to its default pattern.
 // Returns true if the binding was found and reset.
 func (r *Router) Reset(name string) bool {
+	r.lock()
+	defer r.unlock()
+	return r.reset(name)
+}
+
+// reset implements Reset. Caller must hold r.mu.
+func (r *Router) reset(name string) bool {
 	binding, ok := r.namedBindings[name]
 	if !ok {
+		if m, local, ok := r.mountFor(name); ok {
+			return m.sub.Reset(local)
+		}
 		return false
 	}
 
+	binding.source = ""
 	if binding.currentPattern == binding.defaultPattern {
 		return true // Already at default
 	}
 
-	return r.Rebind(name, binding.defaultPattern)
+	return r.rebind(name, binding.defaultPattern)
 }
 
 // ResetAll restores all named bindings to their defaults.
 func (r *Router) ResetAll() {
+	r.lock()
+	defer r.unlock()
 	for name := range r.namedBindings {
-		r.Reset(name)
+		r.reset(name)
+	}
+	for _, m := range r.mounts {
+		m.sub.ResetAll()
 	}
 }
 
-// Bindings returns all named bindings in registration order.
+// Bindings returns all named bindings in registration order, followed by
+// the bindings of mounted sub-routers (namespaced, with the mount prefix).
+// For a router created with Inherit, the parent's bindings come first, with
+// any the child overrides replaced by the child's version.
 func (r *Router) Bindings() []Binding {
-	bindings := make([]Binding, 0, len(r.bindingOrder))
-	for _, name := range r.bindingOrder {
-		if b, ok := r.namedBindings[name]; ok {
-			bindings = append(bindings, Binding{
+	resolved := r.resolvedBindings()
+	bindings := make([]Binding, len(resolved))
+	for i, b := range resolved {
+		bindings[i] = b.Binding
+	}
+	return bindings
+}
+
+// BindingsIn returns Bindings with patterns formatted by f: a Notation, to
+// show bindings in the user's preferred syntax, or a LabelStyle for help
+// screens. Aliases are expanded.
+//
+// Example:
+//
+//	for _, b := range router.BindingsIn(riffkey.LabelSymbols) {
+//	    fmt.Printf("%s %s  ", b.Pattern, b.Name) // ⌃S save
+//	}
+func (r *Router) BindingsIn(f KeyFormatter) []Binding {
+	resolved := r.resolvedBindings()
+	bindings := make([]Binding, len(resolved))
+	for i, b := range resolved {
+		bindings[i] = b.Binding
+		bindings[i].Pattern = f.Format(b.keys)
+		bindings[i].DefaultPattern = f.Format(b.defaultKeys)
+	}
+	return bindings
+}
+
+// resolvedBinding is a Binding together with the keys its patterns resolve to.
+type resolvedBinding struct {
+	Binding
+	keys        []Key
+	defaultKeys []Key
+}
+
+// resolvedBindings implements Bindings.
+func (r *Router) resolvedBindings() []resolvedBinding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bindings := make([]resolvedBinding, 0, len(r.bindingOrder))
+	listed := func(name string) bool {
+		return slices.ContainsFunc(bindings, func(b resolvedBinding) bool { return b.Name == name })
+	}
+	own := func(name string, b *namedBinding) resolvedBinding {
+		return resolvedBinding{
+			Binding: Binding{
 				Name:           name,
 				Pattern:        b.currentPattern,
 				DefaultPattern: b.defaultPattern,
-			})
+				Source:         b.source,
+				Description:    b.description,
+				Repeatable:     b.repeatable,
+			},
+			keys:        r.patternKeys(b.currentPattern),
+			defaultKeys: r.patternKeys(b.defaultPattern),
+		}
+	}
+
+	if r.parent != nil {
+		for _, b := range r.parent.resolvedBindings() {
+			if override, ok := r.namedBindings[b.Name]; ok {
+				b = own(b.Name, override)
+			}
+			bindings = append(bindings, b)
+		}
+	}
+	for _, name := range r.bindingOrder {
+		if r.parent != nil && listed(name) {
+			continue // listed in the parent's position
+		}
+		if b, ok := r.namedBindings[name]; ok {
+			bindings = append(bindings, own(name, b))
+		}
+	}
+	for _, m := range r.mounts {
+		for _, b := range m.sub.resolvedBindings() {
+			rb := resolvedBinding{
+				Binding: Binding{
+					Name:           m.qualify(b.Name),
+					DefaultPattern: r.mountPattern(m, b.DefaultPattern),
+					Source:         b.Source,
+					Description:    b.Description,
+					Repeatable:     b.Repeatable,
+				},
+				defaultKeys: append(slices.Clone(m.keys), b.defaultKeys...),
+			}
+			if !b.Unbound() {
+				rb.Pattern = r.mountPattern(m, b.Pattern)
+				rb.keys = append(slices.Clone(m.keys), b.keys...)
+			}
+			bindings = append(bindings, rb)
 		}
 	}
 	return bindings
 }
 
+// mountPattern writes pattern, one of m's sub-router's, behind m's prefix in
+// r's notation. Caller must hold r.mu.
+func (r *Router) mountPattern(m mount, pattern string) string {
+	if m.sub.notation != r.notation {
+		pattern = r.notation.Format(m.sub.notation.Parse(m.sub.expanded(pattern)))
+	}
+	return r.notation.join(m.prefix, pattern)
+}
+
 // BindingsMap returns current bindings as a map (for serialization to config).
 func (r *Router) BindingsMap() map[string]string {
-	m := make(map[string]string, len(r.namedBindings))
-	for name, b := range r.namedBindings {
-		m[name] = b.currentPattern
+	bindings := r.Bindings()
+	m := make(map[string]string, len(bindings))
+	for _, b := range bindings {
+		m[b.Name] = b.Pattern
 	}
 	return m
 }
 
 // DefaultBindingsMap returns default bindings as a map.
 func (r *Router) DefaultBindingsMap() map[string]string {
-	m := make(map[string]string, len(r.namedBindings))
-	for name, b := range r.namedBindings {
-		m[name] = b.defaultPattern
+	bindings := r.Bindings()
+	m := make(map[string]string, len(bindings))
+	for _, b := range bindings {
+		m[b.Name] = b.DefaultPattern
 	}
 	return m
 }
@@ -833,62 +1766,132 @@ func ConfigPath() string {
 	return filepath.Join(configDir, "riffkey.toml")
 }
 
-// LoadBindings loads bindings from the shared config file.
-// It merges: defaults → global section → app-specific section.
-// Missing file or sections are silently ignored.
-func (r *Router) LoadBindings(appName string) error {
-	return r.LoadBindingsFrom(ConfigPath(), appName)
-}
-
-// LoadBindingsFrom loads bindings from a specific config file.
-func (r *Router) LoadBindingsFrom(path, appName string) error {
-	if path == "" {
-		return nil
-	}
-
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // Missing config is fine
+// ConfigPaths returns the default config sources in order of increasing
+// precedence:
+//
+//  1. riffkey.toml in each $XDG_CONFIG_DIRS directory (default /etc/xdg),
+//     for organisation-wide defaults
+//  2. the user file, ConfigPath()
+//  3. .riffkey.toml in the working directory or its nearest ancestor, for
+//     per-repository settings
+//  4. the files listed in $RIFFKEY_CONFIG
+func ConfigPaths() []string {
+	var paths []string
+
+	dirs := os.Getenv("XDG_CONFIG_DIRS")
+	if dirs == "" {
+		dirs = "/etc/xdg"
+	}
+	system := filepath.SplitList(dirs)
+	for i := len(system) - 1; i >= 0; i-- { // the first dir is the most important
+		if system[i] != "" {
+			paths = append(paths, filepath.Join(system[i], "riffkey.toml"))
 		}
-		return err
 	}
 
-	// Parse into a generic map first
-	var raw map[string]any
-	if _, err := toml.Decode(string(data), &raw); err != nil {
-		return err
+	if user := ConfigPath(); user != "" {
+		paths = append(paths, user)
 	}
 
-	// Apply global aliases
-	if aliases, ok := raw["aliases"].(map[string]any); ok {
-		for name, expansion := range aliases {
-			if s, ok := expansion.(string); ok {
-				r.SetAlias(name, s)
+	if dir, err := os.Getwd(); err == nil {
+		for {
+			project := filepath.Join(dir, ".riffkey.toml")
+			if _, err := os.Stat(project); err == nil {
+				paths = append(paths, project)
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
 			}
+			dir = parent
 		}
 	}
 
-	// Apply global bindings
-	if global, ok := raw["global"].(map[string]any); ok {
-		for name, pattern := range global {
-			if s, ok := pattern.(string); ok {
-				r.Rebind(name, s)
-			}
+	for _, p := range filepath.SplitList(os.Getenv("RIFFKEY_CONFIG")) {
+		if p != "" {
+			paths = append(paths, p)
 		}
 	}
+	return paths
+}
 
-	// Apply app-specific bindings (override global)
-	// Supports dotted names like "browse.toc" which map to [browse.toc] in TOML
-	if appSection := getNestedSection(raw, appName); appSection != nil {
-		for name, pattern := range appSection {
-			if s, ok := pattern.(string); ok {
-				r.Rebind(name, s)
-			}
-		}
+// LoadBindings loads bindings from the config sources given by ConfigPaths.
+// It merges: defaults → each source in turn, each applying its global
+// section then the app-specific section.
+// Missing files or sections are silently ignored.
+//
+// Apps with several modes, each its own named Router, pass them as modes:
+// [global.<name>] and [<app>.<name>] sections then apply to the router with
+// that name, after [global] and [<app>] respectively. A name set on r itself
+// works the same way.
+//
+// Example:
+//
+//	normal := riffkey.NewRouter().Name("normal")
+//	insert := riffkey.NewRouter().Name("insert")
+//	normal.LoadBindings("myapp", insert) // [myapp.normal], [myapp.insert]
+func (r *Router) LoadBindings(appName string, modes ...*Router) error {
+	_, err := r.LoadBindingsFromPaths(ConfigPaths(), appName, modes...)
+	return err
+}
+
+// LoadBindingsFrom loads bindings from a specific config file.
+func (r *Router) LoadBindingsFrom(path, appName string, modes ...*Router) error {
+	_, err := r.LoadBindingsFromPaths([]string{path}, appName, modes...)
+	return err
+}
+
+// LoadBindingsReport loads bindings like LoadBindingsFrom and returns a
+// Diagnostic for every entry it saw, in file order, explaining whether it was
+// applied or why not. Entries that can't be applied are still skipped rather
+// than failing the load; the error is only for unreadable or malformed files.
+//
+// Example:
+//
+//	diags, err := router.LoadBindingsReport(riffkey.ConfigPath(), "myapp")
+//	for _, d := range diags {
+//	    if d.Outcome != riffkey.OutcomeApplied {
+//	        log.Println(d) // riffkey.toml:4:1: [myapp] quitt: unknown action
+//	    }
+//	}
+func (r *Router) LoadBindingsReport(path, appName string, modes ...*Router) ([]Diagnostic, error) {
+	return r.LoadBindingsFromPaths([]string{path}, appName, modes...)
+}
+
+// LoadBindingsFromPaths loads bindings from several config files, lowest
+// precedence first. Each file is applied completely (aliases, global
+// section, app section) before the next, so a later file always wins, and a
+// file's includes are applied before the file itself. Every file is parsed
+// before anything is applied: if one is malformed, the router is left
+// untouched. The Source of each Binding names the file that set it.
+//
+// The config is applied to forks of the routers, and each router's new
+// bindings are swapped in at once, so a key dispatched meanwhile never sees
+// half of it. Register bindings before loading: ones registered on another
+// goroutine while the load runs are replaced by the swap.
+//
+// It returns diagnostics for all files, as LoadBindingsReport does.
+func (r *Router) LoadBindingsFromPaths(paths []string, appName string, modes ...*Router) ([]Diagnostic, error) {
+	files, err := readConfigs(paths)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// Apply to forks, then swap each router's new bindings in at once so
+	// matching never sees a half-applied config.
+	routers := append([]*Router{r}, modes...)
+	forks := make([]*Router, len(routers))
+	seen := make(map[*Router]*Router)
+	for i, m := range routers {
+		forks[i] = m.fork(seen)
+	}
+	diags := forks[0].applyConfigs(files, appName, forks[1:])
+	done := make(map[*Router]bool)
+	for i, m := range routers {
+		m.adopt(forks[i], done)
+	}
+	return diags, nil
 }
 
 // getNestedSection retrieves a section from a nested map using dot notation.
@@ -907,12 +1910,34 @@ func getNestedSection(raw map[string]any, path string) map[string]any {
 }
 
 // WriteDefaultBindings writes a TOML config template with all bindings commented out.
-func (r *Router) WriteDefaultBindings(w io.Writer, appName string) error {
+// When mode routers are given (as for LoadBindings), each named router gets
+// its own [<app>.<name>] section; an unnamed r keeps the [<app>] section.
+func (r *Router) WriteDefaultBindings(w io.Writer, appName string, modes ...*Router) error {
 	var sb strings.Builder
 
-	sb.WriteString("[" + appName + "]\n")
-	for _, b := range r.Bindings() {
-		sb.WriteString("# " + b.Name + " = \"" + escapeTomlString(b.DefaultPattern) + "\"\n")
+	sb.WriteString("# Uncomment a line to rebind it; set it to \"\" or false to unbind.\n\n")
+
+	written := make(map[string]bool)
+	for i, m := range append([]*Router{r}, modes...) {
+		section := appName
+		switch {
+		case len(modes) > 0 && m.name != "":
+			section += "." + m.name
+		case i > 0:
+			continue // an unnamed mode has no section
+		}
+		if written[section] {
+			continue
+		}
+		written[section] = true
+
+		if len(written) > 1 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("[" + section + "]\n")
+		for _, b := range m.Bindings() {
+			sb.WriteString("# " + b.Name + " = \"" + escapeTomlString(b.DefaultPattern) + "\"\n")
+		}
 	}
 
 	_, err := w.Write([]byte(sb.String()))
@@ -948,32 +1973,178 @@ func (r *Router) NoCounts() *Router {
 	return r
 }
 
-// match attempts to match a sequence of keys.
-func (r *Router) match(keys []Key) (handler Handler, consumed int, partial bool) {
-	node := r.root
-	var lastHandler Handler
+// SetLayout applies keyboard-layout rules when normalising keys, so that for
+// example "<S-1>" matches the '!' a US keyboard sends. Registered patterns are
+// re-normalised. Pass nil to use NormalizeKey alone.
+func (r *Router) SetLayout(l Layout) *Router {
+	r.lock()
+	defer r.unlock()
+	r.layout = l
+	r.rebuild()
+	return r
+}
+
+// SetNotation selects the syntax of patterns given to Handle, HandleNamed,
+// Rebind and Mount: vim (the default), Emacs ("C-x C-s") or VS Code
+// ("ctrl+x ctrl+s"). Registered patterns are re-parsed in the new notation.
+func (r *Router) SetNotation(n Notation) *Router {
+	r.lock()
+	defer r.unlock()
+	r.notation = n
+	r.rebuild()
+	return r
+}
+
+// WithNotation sets the router's pattern notation (see Router.SetNotation).
+func WithNotation(n Notation) RouterOption {
+	return func(r *Router) {
+		r.notation = n
+	}
+}
+
+// WithLayout sets the router's keyboard layout (see Router.SetLayout).
+func WithLayout(l Layout) RouterOption {
+	return func(r *Router) {
+		r.layout = l
+	}
+}
+
+// match attempts to match a sequence of keys against the router's trie, its
+// mounts and, for inheriting routers, its parent.
+func (r *Router) match(keys []Key) (h hit, consumed int, partial bool) {
+	return r.matchShadowed(keys, nil)
+}
+
+// matchShadowed is match ignoring the named bindings for which shadowed
+// reports true, both as matches and as sequences worth waiting for. A nil
+// shadowed ignores none.
+func (r *Router) matchShadowed(keys []Key, shadowed func(name string) bool) (h hit, consumed int, partial bool) {
+	s := r.snapshot()
+	var handler Handler
+	var name string
+	handler, name, consumed, partial = s.matchTrie(keys, shadowed)
+	if handler != nil {
+		h = hit{handler: handler, name: name, owner: r, repeat: s.repeatable[name]}
+	}
+
+	for _, m := range s.mounts {
+		if !m.sub.IsEnabled() || len(m.keys) == 0 {
+			continue
+		}
+		if len(keys) < len(m.keys) {
+			// still typing the prefix
+			if s.hasPrefix(m.keys, keys) {
+				partial = true
+			}
+			continue
+		}
+		if !s.hasPrefix(keys, m.keys) {
+			continue
+		}
+		var subShadowed func(string) bool
+		if shadowed != nil {
+			subShadowed = func(name string) bool { return shadowed(m.qualify(name)) }
+		}
+		sh, c, p := m.sub.matchShadowed(keys[len(m.keys):], subShadowed)
+		if p {
+			partial = true
+		}
+		if sh.handler != nil && len(m.keys)+c >= consumed {
+			if sh.name != "" {
+				sh.name = m.qualify(sh.name)
+			}
+			h = sh
+			consumed = len(m.keys) + c
+		}
+	}
+
+	if r.parent != nil {
+		// the child's named bindings shadow the parent's, so the parent
+		// neither matches them nor waits for them
+		ph, c, p := r.parent.matchShadowed(keys, func(name string) bool {
+			return s.named[name] || shadowed != nil && shadowed(name)
+		})
+		if p {
+			partial = true
+		}
+		// the child wins ties
+		if ph.handler != nil && c > consumed {
+			if ph.owner == r.parent {
+				ph.owner = r // inherited bindings run under the child's hooks
+			}
+			h = ph
+			consumed = c
+		}
+	}
+	return h, consumed, partial
+}
+
+// hasPrefix reports whether keys starts with prefix under s's layout.
+func (s *snapshot) hasPrefix(keys, prefix []Key) bool {
+	for i, k := range prefix {
+		if s.layout.Normalize(keys[i]) != k {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTrie walks the router's own trie, ignoring mounts and parents, and
+// the named bindings for which shadowed, if not nil, reports true.
+func (s *snapshot) matchTrie(keys []Key, shadowed func(name string) bool) (handler Handler, name string, consumed int, partial bool) {
+	node := s.root
+	var last *trieNode
 	var lastConsumed int
 
 	for i, k := range keys {
-		child, exists := node.children[k]
+		child, exists := node.children[s.layout.Normalize(k)]
 		if !exists {
-			if lastHandler != nil {
-				return lastHandler, lastConsumed, false
+			if last != nil {
+				return last.handler, last.name, lastConsumed, false
 			}
-			return nil, 0, false
+			return nil, "", 0, false
 		}
 		node = child
-		if node.handler != nil {
-			lastHandler = node.handler
+		if node.live(shadowed) {
+			last = node
 			lastConsumed = i + 1
 		}
 	}
 
-	partial = len(node.children) > 0
-	return lastHandler, lastConsumed, partial
+	if shadowed == nil {
+		partial = len(node.children) > 0
+	} else {
+		partial = node.leadsTo(shadowed)
+	}
+	if last == nil {
+		return nil, "", 0, partial
+	}
+	return last.handler, last.name, lastConsumed, partial
+}
+
+// live reports whether n has a handler that shadowed doesn't hide.
+func (n *trieNode) live(shadowed func(name string) bool) bool {
+	return n.handler != nil && (shadowed == nil || n.name == "" || !shadowed(n.name))
+}
+
+// leadsTo reports whether any node below n has a handler that shadowed
+// doesn't hide.
+func (n *trieNode) leadsTo(shadowed func(name string) bool) bool {
+	for _, c := range n.children {
+		if c.live(shadowed) || c.leadsTo(shadowed) {
+			return true
+		}
+	}
+	return false
 }
 
 // ParsePattern parses a vim-style pattern string into a sequence of Keys.
+//
+// As in vim, a <...> span that isn't a valid key notation is not special:
+// its '<' is taken literally, so "<>" or "<<" bind those characters. Literal
+// characters that would otherwise be awkward can be written as <lt> (<),
+// <gt> (>), <Bslash> (\), <Bar> (|), <DQuote> (") and <Minus> (-), with or
+// without modifiers (e.g. "<C-lt>").
 func ParsePattern(pattern string) []Key {
 	if pattern == "" {
 		return nil
@@ -992,11 +2163,11 @@ func ParsePattern(pattern string) []Key {
 			}
 			if end < len(runes) {
 				// Parse <...> sequence
-				inner := string(runes[i+1 : end])
-				key := parseVimKey(inner)
-				keys = append(keys, key)
-				i = end + 1
-				continue
+				if key, ok := parseVimKey(string(runes[i+1 : end])); ok {
+					keys = append(keys, key)
+					i = end + 1
+					continue
+				}
 			}
 		}
 		// Regular character
@@ -1007,43 +2178,118 @@ func ParsePattern(pattern string) []Key {
 	return keys
 }
 
-// parseVimKey parses the content inside <...>
-func parseVimKey(s string) Key {
-	var key Key
-	parts := strings.Split(s, "-")
+// vimToRune maps key notation names for literal characters.
+var vimToRune = map[string]rune{
+	"lt":     '<',
+	"gt":     '>',
+	"bslash": '\\',
+	"bar":    '|',
+	"dquote": '"',
+	"minus":  '-',
+}
 
-	for i, part := range parts {
-		lower := strings.ToLower(part)
+// runeToVim holds the canonical names FormatPattern uses for characters that
+// can't be written literally.
+var runeToVim = map[rune]string{
+	'<':  "lt",
+	'>':  "gt",
+	'\\': "Bslash",
+	'|':  "Bar",
+	'"':  "DQuote",
+}
 
-		// Check for modifiers (only valid before the final part)
-		if i < len(parts)-1 {
-			switch lower {
-			case "c":
-				key.Mod |= ModCtrl
-				continue
-			case "a", "m": // A for Alt, M for Meta (same thing)
-				key.Mod |= ModAlt
-				continue
-			case "s":
-				key.Mod |= ModShift
-				continue
-			}
+// parseVimKey parses the content inside <...>. It reports false if s is not
+// a valid key notation: optional C-, A-/M-, S- and D- modifiers followed by a
+// special key name, a literal character name or exactly one character.
+func parseVimKey(s string) (Key, bool) {
+	var key Key
+
+	// Modifiers: a single letter followed by '-', as long as something follows
+	for len(s) > 2 && s[1] == '-' {
+		switch s[0] {
+		case 'c', 'C':
+			key.Mod |= ModCtrl
+		case 'a', 'A', 'm', 'M': // A for Alt, M for Meta (same thing)
+			key.Mod |= ModAlt
+		case 's', 'S':
+			key.Mod |= ModShift
+		case 'd', 'D': // D for Super/Cmd, as in Neovim
+			key.Mod |= ModSuper
+		default:
+			return Key{}, false
 		}
+		s = s[2:]
+	}
 
-		// Final part - check if it's a special key
-		if special, ok := vimToSpecial[lower]; ok {
-			key.Special = special
-		} else if len(part) == 1 {
-			key.Rune = rune(part[0])
-		} else {
-			// Unknown, treat first char as the key
-			if len(part) > 0 {
-				key.Rune = rune(part[0])
-			}
+	// Final part - a special key, a named literal or a single character
+	lower := strings.ToLower(s)
+	if special, ok := vimToSpecial[lower]; ok {
+		key.Special = special
+		return key, true
+	}
+	if r, ok := vimToRune[lower]; ok {
+		key.Rune = r
+		return key, true
+	}
+	if r, size := utf8.DecodeRuneInString(s); size == len(s) && r != utf8.RuneError {
+		if key.Mod == ModNone {
+			// <x> is just x; only modified single characters need brackets
+			return Key{}, false
 		}
+		key.Rune = r
+		return key, true
+	}
+	return Key{}, false
+}
+
+// FormatPattern formats keys as a canonical vim-style pattern, the inverse of
+// ParsePattern: ParsePattern(FormatPattern(keys)) returns keys exactly.
+// Characters that ParsePattern would treat specially are written with their
+// names (<lt>, <Bslash>, <Bar>, <DQuote>). Paste events and empty keys have
+// no pattern form and are omitted.
+func FormatPattern(keys []Key) string {
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(formatVimKey(k))
+	}
+	return sb.String()
+}
+
+// formatVimKey formats a single key for FormatPattern.
+func formatVimKey(k Key) string {
+	if k.IsPaste() || (k.Special == SpecialNone && k.Rune == 0) {
+		return ""
+	}
+
+	var name string
+	switch {
+	case k.Special != SpecialNone:
+		name = specialToVim[k.Special]
+	case runeToVim[k.Rune] != "":
+		name = runeToVim[k.Rune]
+	case k.Mod == ModNone:
+		return string(k.Rune)
+	default:
+		name = string(k.Rune)
 	}
 
-	return key
+	var sb strings.Builder
+	sb.WriteByte('<')
+	if k.Mod&ModCtrl != 0 {
+		sb.WriteString("C-")
+	}
+	if k.Mod&ModAlt != 0 {
+		sb.WriteString("A-")
+	}
+	if k.Mod&ModShift != 0 {
+		sb.WriteString("S-")
+	}
+	if k.Mod&ModSuper != 0 {
+		sb.WriteString("D-")
+	}
+	sb.WriteString(name)
+	sb.WriteByte('>')
+	return sb.String()
 }
 
 // frame is one slot in the router stack. It owns a primary router plus any
@@ -1060,17 +2306,21 @@ type frame struct {
 // current position. Ties on consumed-length resolve in favour of later-
 // iterated routers (primary first, then subs in attach order) so that
 // sub-routers shadow the primary when patterns overlap.
-func (f *frame) match(keys []Key) (handler Handler, consumed int, partial bool, matched *Router) {
+//
+// matched is the frame router that produced the match; h.owner is the
+// router whose hooks wrap the handler, which differs from matched for
+// handlers reached through Mount.
+func (f *frame) match(keys []Key) (h hit, consumed int, partial bool, matched *Router) {
 	consider := func(r *Router) {
 		if r == nil || !r.IsEnabled() {
 			return
 		}
-		h, c, p := r.match(keys)
+		rh, c, p := r.match(keys)
 		if p {
 			partial = true
 		}
-		if h != nil && c >= consumed {
-			handler = h
+		if rh.handler != nil && c >= consumed {
+			h = rh
 			consumed = c
 			matched = r
 		}
@@ -1150,6 +2400,33 @@ type Input struct {
 	pending       Handler
 	pendingKeys   []Key
 	pendingRouter *Router // router that owns the pending handler
+	pendingOwner  *Router // router that registered it (differs for mounts)
+	pendingName   string  // binding name of the pending handler, if named
+	pendingRepeat bool    // the pending binding is repeatable
+
+	modes map[string]*Router // routers registered with AddMode
+
+	// Event subscribers and events waiting to be delivered to them
+	subscribers []*subscriber
+	events      []Event
+	delivering  bool
+
+	middleware []Middleware
+
+	onPanic func(Panic)
+
+	// Handler invocations waiting to be passed to the executor
+	executor func(func())
+	jobs     []func()
+
+	// SetHoldKeys: keys held while handler jobs are outstanding
+	holdKeys bool
+	inflight int // jobs queued with holdKeys on that haven't finished
+	held     []heldKey
+
+	// Channel mode: matches waiting to be sent on the channel
+	matches  chan Resolved
+	resolved []Resolved
 
 	// Key interceptor for macro recording
 	keyInterceptor func(Key)
@@ -1157,7 +2434,24 @@ type Input struct {
 	// Macro recording
 	macroBuffer []Key // keys being recorded
 	recording   bool
-	executing   bool // true during macro execution (prevents recording)
+	executing   int // depth of nested macro execution (keys aren't recorded while > 0)
+
+	recordMatches bool // record resolved matches instead of raw keys
+	lastStart     int  // macroBuffer index where the last resolved keys start
+	inTrigger     bool // a handler fired while recording is running
+	triggerStart  int  // macroBuffer index where its keys start
+
+	// Macro registers
+	registers      map[string]Macro
+	recordRegister string // register StopRecording saves to
+	lastRegister   string // last register executed, for "@"
+	macroDepth     int    // limit on nested playback; 0 means defaultMacroDepth
+
+	// Dot-repeat
+	lastChange  *change
+	changeDepth int  // stack depth when lastChange fired
+	capturing   bool // keys typed in frames lastChange pushed go to lastChange.typed
+	repeating   bool // true during Repeat
 }
 
 // NewInput creates a new Input with the given root router.
@@ -1173,19 +2467,28 @@ func NewInput(root *Router) *Input {
 // Any sub-routers attached to previous frames are shadowed until Pop.
 func (i *Input) Push(r *Router) {
 	i.mu.Lock()
+	defer i.deliver()
 	defer i.mu.Unlock()
+	prev := i.mode()
 	i.clearBuffer()
 	i.stack = append(i.stack, &frame{primary: r})
+	i.modeChanged(prev)
 }
 
 // Pop removes the top frame from the stack, discarding any sub-routers
 // attached to it.
 func (i *Input) Pop() {
 	i.mu.Lock()
+	defer i.deliver()
 	defer i.mu.Unlock()
 	if len(i.stack) > 1 {
+		prev := i.mode()
 		i.clearBuffer()
 		i.stack = i.stack[:len(i.stack)-1]
+		if i.capturing && len(i.stack) <= i.changeDepth {
+			i.capturing = false // the change is complete
+		}
+		i.modeChanged(prev)
 	}
 }
 
@@ -1195,13 +2498,16 @@ func (i *Input) Pop() {
 // Use this to swap between views without affecting modal state.
 func (i *Input) SetRouter(r *Router) {
 	i.mu.Lock()
+	defer i.deliver()
 	defer i.mu.Unlock()
+	prev := i.mode()
 	i.clearBuffer()
 	if len(i.stack) == 0 {
 		i.stack = []*frame{{primary: r}}
 	} else {
 		i.stack[0] = &frame{primary: r}
 	}
+	i.modeChanged(prev)
 }
 
 // Current returns the primary router of the currently active frame.
@@ -1289,12 +2595,24 @@ type Macro []Key
 func (i *Input) StartRecording() {
 	i.mu.Lock()
 	defer i.mu.Unlock()
+	i.startRecording("")
+}
+
+// startRecording begins a recording saved to register, or to none if
+// register is "". Caller must hold i.mu.
+func (i *Input) startRecording(register string) {
 	i.recording = true
+	i.recordRegister = register
 	i.macroBuffer = nil
+	i.lastStart = 0
 }
 
-// StopRecording stops recording and returns the recorded keys.
-// The last key (the stop trigger) is automatically excluded.
+// StopRecording stops recording and returns the recorded keys, saving them
+// to the register given to StartRecordingRegister, if any.
+// The stop trigger is excluded: called from a handler, the keys that fired
+// it, count included, are dropped, so "2<Leader>q" leaves nothing behind.
+// Called from elsewhere, keys still waiting for a match are dropped, or if
+// there are none, the keys of the last match.
 func (i *Input) StopRecording() Macro {
 	i.mu.Lock()
 	defer i.mu.Unlock()
@@ -1302,12 +2620,12 @@ func (i *Input) StopRecording() Macro {
 		return nil
 	}
 	i.recording = false
-	// Exclude the last key (the stop trigger)
-	if len(i.macroBuffer) > 0 {
-		i.macroBuffer = i.macroBuffer[:len(i.macroBuffer)-1]
-	}
-	macro := i.macroBuffer
+	macro := i.macroBuffer[:min(i.trigger(), len(i.macroBuffer))]
 	i.macroBuffer = nil
+	if i.recordRegister != "" {
+		i.setRegister(i.recordRegister, macro)
+		i.recordRegister = ""
+	}
 	return macro
 }
 
@@ -1320,25 +2638,56 @@ func (i *Input) IsRecording() bool {
 
 // ExecuteMacro dispatches all keys in the macro.
 // Keys dispatched during execution are not recorded (prevents nested recording).
+// A macro that plays macros from its handlers nests; playback nested deeper
+// than SetMacroDepth allows is skipped, so a macro that runs itself stops.
 func (i *Input) ExecuteMacro(macro Macro) {
+	i.execute(macro, 1)
+}
+
+// execute dispatches macro count times and reports whether it did, which it
+// doesn't if that would nest playback too deeply.
+func (i *Input) execute(macro Macro, count int) bool {
 	i.mu.Lock()
-	i.executing = true
+	limit := i.macroDepth
+	if limit <= 0 {
+		limit = defaultMacroDepth
+	}
+	if i.executing >= limit {
+		i.mu.Unlock()
+		return false
+	}
+	i.executing++
 	i.mu.Unlock()
+	defer func() {
+		i.mu.Lock()
+		i.executing--
+		i.mu.Unlock()
+	}()
 
-	for _, key := range macro {
-		i.Dispatch(key)
+	for range count {
+		for _, key := range macro {
+			i.Dispatch(key)
+		}
 	}
-
-	i.mu.Lock()
-	i.executing = false
-	i.mu.Unlock()
+	return true
 }
 
 // Dispatch processes a key through the current router.
 // Returns true if the key was handled.
 func (i *Input) Dispatch(key Key) bool {
+	key = NormalizeKey(key)
 	i.mu.Lock()
+	if i.holding() {
+		i.held = append(i.held, heldKey{key, i.executing, i.repeating})
+		i.mu.Unlock()
+		return true
+	}
+	return i.dispatch(key)
+}
 
+// dispatch processes a normalised key. Caller must hold i.mu, which
+// dispatch releases.
+func (i *Input) dispatch(key Key) bool {
 	// Call interceptor first
 	if i.keyInterceptor != nil {
 		fn := i.keyInterceptor
@@ -1347,16 +2696,19 @@ func (i *Input) Dispatch(key Key) bool {
 		i.mu.Lock()
 	}
 
-	// Record key if recording (but not during macro execution)
-	if i.recording && !i.executing {
+	// Record key if recording (but not during macro execution or repeat)
+	if i.recording && !i.recordMatches && i.executing == 0 && !i.repeating {
 		i.macroBuffer = append(i.macroBuffer, key)
 	}
+	i.captureChange(key)
 
+	defer i.deliver()
 	defer i.mu.Unlock()
 
 	if len(i.stack) == 0 {
 		return false
 	}
+	i.emit(Event{Kind: EventKey, Key: key})
 
 	top := i.stack[len(i.stack)-1]
 
@@ -1364,6 +2716,7 @@ func (i *Input) Dispatch(key Key) bool {
 	if i.isCountDigit(key) && len(i.buffer) == 0 && !top.noCountsActive() {
 		// Accumulate count prefix
 		i.countBuffer += string(key.Rune)
+		i.emit(Event{Kind: EventCount, Key: key, Count: i.parseCount()})
 		return true
 	}
 
@@ -1377,43 +2730,38 @@ func (i *Input) Dispatch(key Key) bool {
 	}
 	i.pending = nil
 	i.pendingKeys = nil
+	i.pendingName = ""
+	i.pendingRepeat = false
 
 	i.buffer = append(i.buffer, key)
 
-	handler, consumed, partial, matched := top.match(i.buffer)
+	h, consumed, partial, matched := top.match(i.buffer)
+	handler, owner := h.handler, h.owner
 
 	// If we were pending and the new key doesn't extend the match AND
 	// there's no partial match possible, the sequence is broken
 	if wasPending && consumed < len(i.buffer) && !partial {
+		i.emit(Event{Kind: EventBroken, Key: key, Keys: slices.Clone(i.buffer[:len(i.buffer)-1]), Count: i.parseCount()})
 		i.buffer = nil
 		i.countBuffer = ""
+		i.recordResolved(1, "", []Key{key})
+		i.emit(Event{Kind: EventUnmatched, Key: key, Keys: []Key{key}, Count: 1})
 		// Try unmatched handler for the new key
-		if um := top.unmatchedHandler(); um != nil {
-			i.mu.Unlock()
-			handled := um(key)
-			i.mu.Lock()
-			return handled
-		}
-		return false
+		return i.fallback(top, key, []Key{key})
 	}
 
 	if handler != nil && !partial {
 		// Complete match, no ambiguity - fire immediately
 		matchedKeys := make([]Key, consumed)
 		copy(matchedKeys, i.buffer[:consumed])
+		i.recordResolved(len(i.countBuffer)+len(i.buffer), i.countBuffer, matchedKeys)
 		i.buffer = i.buffer[consumed:]
 		count := i.parseCount()
+		m := Match{Keys: matchedKeys, Count: count, HasCount: i.countBuffer != ""}
 		i.countBuffer = ""
 
-		i.mu.Unlock()
-		for _, fn := range matched.beforeHooks {
-			fn()
-		}
-		handler(Match{Keys: matchedKeys, Count: count})
-		for _, fn := range matched.afterHooks {
-			fn()
-		}
-		i.mu.Lock()
+		i.emit(Event{Kind: EventHandler, Key: key, Keys: matchedKeys, Count: count, Binding: h.name, Router: matched.name})
+		i.fire(h, m, matched)
 		return true
 	}
 
@@ -1423,7 +2771,12 @@ func (i *Input) Dispatch(key Key) bool {
 		i.pendingKeys = make([]Key, consumed)
 		copy(i.pendingKeys, i.buffer[:consumed])
 		i.pendingRouter = matched
+		i.pendingOwner = owner
+		i.pendingName = h.name
+		i.pendingRepeat = h.repeat
 		pendingCount := i.parseCount()
+		hasCount := i.countBuffer != ""
+		i.emit(Event{Kind: EventAmbiguous, Key: key, Keys: slices.Clone(i.buffer), Count: pendingCount, Binding: h.name, Router: matched.name})
 
 		i.timer = time.AfterFunc(matched.timeout, func() {
 			i.mu.Lock()
@@ -1435,45 +2788,100 @@ func (i *Input) Dispatch(key Key) bool {
 				h := i.pending
 				keys := i.pendingKeys
 				r := i.pendingRouter
+				o := i.pendingOwner
+				name := i.pendingName
+				rep := i.pendingRepeat
 				i.pending = nil
 				i.pendingKeys = nil
 				i.pendingRouter = nil
+				i.pendingOwner = nil
+				i.pendingName = ""
+				i.pendingRepeat = false
+				i.recordResolved(len(i.countBuffer)+len(i.buffer), i.countBuffer, keys)
 				i.buffer = i.buffer[len(keys):]
 				i.countBuffer = ""
-				i.mu.Unlock()
-				for _, fn := range r.beforeHooks {
-					fn()
-				}
-				h(Match{Keys: keys, Count: pendingCount})
-				for _, fn := range r.afterHooks {
-					fn()
-				}
-				return
+				i.emit(Event{Kind: EventTimeout, Keys: keys, Count: pendingCount, Binding: name, Router: r.name})
+				i.emit(Event{Kind: EventHandler, Keys: keys, Count: pendingCount, Binding: name, Router: r.name})
+				i.fire(hit{handler: h, name: name, owner: o, repeat: rep}, Match{Keys: keys, Count: pendingCount, HasCount: hasCount}, r)
 			}
 			i.mu.Unlock()
+			i.deliver()
 		})
 		return true
 	}
 
 	if partial {
 		// Partial match, no complete handler yet - wait for more input
+		i.emit(Event{Kind: EventPartial, Key: key, Keys: slices.Clone(i.buffer), Count: i.parseCount()})
 		return true
 	}
 
 	// No match at all - try unmatched handler
+	i.emit(Event{Kind: EventUnmatched, Key: key, Keys: slices.Clone(i.buffer), Count: i.parseCount()})
+	keys := i.buffer
+	i.recordResolved(len(i.countBuffer)+len(keys), "", []Key{key})
 	i.buffer = nil
 	i.countBuffer = ""
 
-	if um := top.unmatchedHandler(); um != nil {
-		i.mu.Unlock()
-		handled := um(key)
-		i.mu.Lock()
-		return handled
+	return i.fallback(top, key, keys)
+}
+
+// fire runs a matched handler with its hooks, or in channel mode sends the
+// match instead. Caller must hold i.mu.
+func (i *Input) fire(h hit, m Match, matched *Router) {
+	if i.matches != nil {
+		i.resolve(Resolved{Binding: h.name, Match: m, Router: matched})
+		return
+	}
+	if h.repeat && !i.repeating && !i.capturing {
+		i.lastChange = &change{hit: h, m: m, matched: matched}
+		i.changeDepth = len(i.stack)
+		i.capturing = true
+	}
+	run := i.wrap(h.handler, h.name, matched, h.owner)
+	if i.recording {
+		run = i.triggered(i.lastStart, run)
 	}
+	i.invoke(i.guard(h.name, m.Keys, func() { run(m) }))
+}
 
+// fallback passes key, the last of keys that matched nothing, to the
+// frame's unmatched handler, or in channel mode sends keys instead. Caller
+// must hold i.mu.
+func (i *Input) fallback(top *frame, key Key, keys []Key) bool {
+	if i.matches != nil {
+		i.resolve(Resolved{Unmatched: keys})
+		return false
+	}
+	if um := top.unmatchedHandler(); um != nil {
+		return i.invokeUnmatched(um, key)
+	}
 	return false
 }
 
+// runHandler calls h wrapped in the hooks of the frame router that matched.
+// When the handler was reached through a mount, the mounted router's own
+// hooks run inside the frame router's.
+func runHandler(h Handler, m Match, matched, owner *Router) {
+	for _, fn := range matched.beforeHooks {
+		fn()
+	}
+	if owner != nil && owner != matched {
+		for _, fn := range owner.beforeHooks {
+			fn()
+		}
+	}
+	h(m)
+	if owner != nil && owner != matched {
+		for _, fn := range owner.afterHooks {
+			fn()
+		}
+	}
+	for _, fn := range matched.afterHooks {
+		fn()
+	}
+}
+
 // parseCount returns the count prefix, defaulting to 1.
 func (i *Input) parseCount() int {
 	if i.countBuffer == "" {
@@ -1495,30 +2903,43 @@ func (i *Input) clearBuffer() {
 	i.pending = nil
 	i.pendingKeys = nil
 	i.pendingRouter = nil
+	i.pendingOwner = nil
+	i.pendingName = ""
+	i.pendingRepeat = false
 	i.buffer = nil
 	i.countBuffer = ""
 }
 
-// Flush forces any pending handler to fire immediately.
+// Flush forces any pending handler to fire immediately, with its hooks and
+// middleware.
 func (i *Input) Flush() {
 	i.mu.Lock()
+	defer i.deliver()
 	defer i.mu.Unlock()
 	if i.pending != nil {
 		h := i.pending
 		keys := i.pendingKeys
+		r := i.pendingRouter
+		o := i.pendingOwner
+		name := i.pendingName
+		rep := i.pendingRepeat
 		count := i.parseCount()
+		hasCount := i.countBuffer != ""
+		i.emit(Event{Kind: EventHandler, Keys: keys, Count: count, Binding: name, Router: r.name})
+		i.recordResolved(len(i.countBuffer)+len(i.buffer), i.countBuffer, keys)
 		i.pending = nil
 		i.pendingKeys = nil
 		i.pendingRouter = nil
+		i.pendingOwner = nil
+		i.pendingName = ""
+		i.pendingRepeat = false
 		i.buffer = nil
 		i.countBuffer = ""
 		if i.timer != nil {
 			i.timer.Stop()
 			i.timer = nil
 		}
-		i.mu.Unlock()
-		h(Match{Keys: keys, Count: count})
-		i.mu.Lock()
+		i.fire(hit{handler: h, name: name, owner: o, repeat: rep}, Match{Keys: keys, Count: count, HasCount: hasCount}, r)
 	}
 }
 
@@ -1604,6 +3025,12 @@ func (r *Reader) SetUTF8(enabled bool) *Reader {
 // If bracketed paste mode is enabled in the terminal, pasted content is returned
 // as a single Key with the Paste field populated.
 func (r *Reader) ReadKey() (Key, error) {
+	k, err := r.readKey()
+	return NormalizeKey(k), err
+}
+
+// readKey reads the next key without normalising it.
+func (r *Reader) readKey() (Key, error) {
 	// Ensure we have at least one byte
 	if err := r.ensureBytes(1); err != nil {
 		return Key{}, err
@@ -1881,7 +3308,7 @@ func (r *Reader) parseSingleByte(b byte) Key {
 	case b == 127 || b == 8:
 		return Key{Special: SpecialBackspace}
 	case b == 0:
-		return Key{Rune: ' ', Mod: ModCtrl} // Ctrl+Space
+		return Key{Special: SpecialSpace, Mod: ModCtrl} // Ctrl+Space
 	case b < 27:
 		// Ctrl+A through Ctrl+Z (1-26), includes Ctrl+j (10)
 		return Key{Rune: rune('a' + b - 1), Mod: ModCtrl}
@@ -1979,10 +3406,10 @@ func (r *Reader) parseCSI(b []byte) Key {
 		return Key{Special: SpecialTab, Mod: ModShift} // Shift+Tab
 	}
 
-	// Modified arrows: ESC [ 1 ; mod X
+	// Modified arrows: ESC [ 1 ; mod X, where mod may run to two digits
 	if len(b) >= 4 && b[0] == '1' && b[1] == ';' {
-		mod := r.parseModifier(b[2])
-		switch b[3] {
+		mod := r.parseModifier(string(b[2 : len(b)-1]))
+		switch b[len(b)-1] {
 		case 'A':
 			return Key{Special: SpecialUp, Mod: mod}
 		case 'B':
@@ -2016,9 +3443,7 @@ func (r *Reader) parseTildeSequence(b []byte) Key {
 	var mod Modifier
 	numStr := string(b)
 	if idx := strings.Index(numStr, ";"); idx != -1 {
-		if idx+1 < len(numStr) {
-			mod = r.parseModifier(numStr[idx+1])
-		}
+		mod = r.parseModifier(numStr[idx+1:])
 		numStr = numStr[:idx]
 	}
 
@@ -2087,10 +3512,15 @@ func (r *Reader) parseSS3(b []byte) Key {
 	return Key{Special: SpecialEscape}
 }
 
-// parseModifier converts a modifier number to Modifier flags.
-// Terminal modifier encoding: 1 + (shift?1:0) + (alt?2:0) + (ctrl?4:0)
-func (r *Reader) parseModifier(b byte) Modifier {
-	n := int(b - '1')
+// parseModifier converts a modifier parameter, "2" to "16", to Modifier
+// flags. Terminal modifier encoding: 1 + (shift?1:0) + (alt?2:0) + (ctrl?4:0)
+// + (super?8:0). A parameter that isn't a number gives no modifiers.
+func (r *Reader) parseModifier(param string) Modifier {
+	n, err := strconv.Atoi(param)
+	if err != nil || n < 1 {
+		return 0
+	}
+	n--
 	var mod Modifier
 	if n&1 != 0 {
 		mod |= ModShift
@@ -2101,6 +3531,9 @@ func (r *Reader) parseModifier(b byte) Modifier {
 	if n&4 != 0 {
 		mod |= ModCtrl
 	}
+	if n&8 != 0 {
+		mod |= ModSuper
+	}
 	return mod
 }
 
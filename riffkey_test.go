@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"sync"
@@ -1353,24 +1354,32 @@ func TestReaderAllFunctionKeys(t *testing.T) {
 }
 
 func TestReaderAllModifierCombinations(t *testing.T) {
-	// Terminal modifier encoding: 1 + (shift?1:0) + (alt?2:0) + (ctrl?4:0)
+	// Terminal modifier encoding: 1 + (shift?1:0) + (alt?2:0) + (ctrl?4:0) + (super?8:0)
 	tests := []struct {
 		name    string
-		modNum  byte // modifier number in sequence
+		modNum  string // modifier parameter in sequence
 		wantMod Modifier
 	}{
-		{"shift", '2', ModShift},
-		{"alt", '3', ModAlt},
-		{"shift+alt", '4', ModShift | ModAlt},
-		{"ctrl", '5', ModCtrl},
-		{"ctrl+shift", '6', ModCtrl | ModShift},
-		{"ctrl+alt", '7', ModCtrl | ModAlt},
-		{"ctrl+alt+shift", '8', ModCtrl | ModAlt | ModShift},
+		{"shift", "2", ModShift},
+		{"alt", "3", ModAlt},
+		{"shift+alt", "4", ModShift | ModAlt},
+		{"ctrl", "5", ModCtrl},
+		{"ctrl+shift", "6", ModCtrl | ModShift},
+		{"ctrl+alt", "7", ModCtrl | ModAlt},
+		{"ctrl+alt+shift", "8", ModCtrl | ModAlt | ModShift},
+		{"super", "9", ModSuper},
+		{"super+shift", "10", ModSuper | ModShift},
+		{"super+alt", "11", ModSuper | ModAlt},
+		{"super+alt+shift", "12", ModSuper | ModAlt | ModShift},
+		{"super+ctrl", "13", ModSuper | ModCtrl},
+		{"super+ctrl+shift", "14", ModSuper | ModCtrl | ModShift},
+		{"super+ctrl+alt", "15", ModSuper | ModCtrl | ModAlt},
+		{"super+ctrl+alt+shift", "16", ModSuper | ModCtrl | ModAlt | ModShift},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name+"_arrow", func(t *testing.T) {
-			input := []byte{0x1b, '[', '1', ';', tt.modNum, 'A'}
+			input := []byte("\x1b[1;" + tt.modNum + "A")
 			r := NewReader(bytes.NewReader(input))
 			got, err := r.ReadKey()
 			if err != nil {
@@ -1382,7 +1391,7 @@ func TestReaderAllModifierCombinations(t *testing.T) {
 		})
 
 		t.Run(tt.name+"_pageup", func(t *testing.T) {
-			input := []byte{0x1b, '[', '5', ';', tt.modNum, '~'}
+			input := []byte("\x1b[5;" + tt.modNum + "~")
 			r := NewReader(bytes.NewReader(input))
 			got, err := r.ReadKey()
 			if err != nil {
@@ -1392,6 +1401,18 @@ func TestReaderAllModifierCombinations(t *testing.T) {
 				t.Errorf("got = %+v, want Special=%v Mod=%v", got, SpecialPageUp, tt.wantMod)
 			}
 		})
+
+		t.Run(tt.name+"_delete", func(t *testing.T) {
+			input := []byte("\x1b[3;" + tt.modNum + "~")
+			r := NewReader(bytes.NewReader(input))
+			got, err := r.ReadKey()
+			if err != nil {
+				t.Fatalf("ReadKey() error = %v", err)
+			}
+			if got.Special != SpecialDelete || got.Mod != tt.wantMod {
+				t.Errorf("got = %+v, want Special=%v Mod=%v", got, SpecialDelete, tt.wantMod)
+			}
+		})
 	}
 }
 
@@ -1606,7 +1627,7 @@ func TestReaderSpecialChars(t *testing.T) {
 		{"enter_lf", '\n', Key{Rune: 'j', Mod: ModCtrl}},
 		{"backspace_127", 127, Key{Special: SpecialBackspace}},
 		{"backspace_8", 8, Key{Special: SpecialBackspace}},
-		{"ctrl_space", 0, Key{Rune: ' ', Mod: ModCtrl}},
+		{"ctrl_space", 0, Key{Special: SpecialSpace, Mod: ModCtrl}},
 	}
 
 	for _, tt := range tests {
@@ -1667,18 +1688,18 @@ func TestAliases(t *testing.T) {
 			want:     []string{"g<Nav>j"},
 		},
 		{
-			name:     "no recursive expansion",
+			name:     "recursive expansion",
 			aliases:  map[string]string{"A": "<B>", "B": "x"},
 			patterns: []string{"<A>"},
-			input:    "x",
-			want:     []string{}, // <A> expands to <B> (which parses as 'B'), so 'x' won't match
+			input:    "x", // <A> expands to <B>, which expands to x
+			want:     []string{"<A>"},
 		},
 		{
-			name:     "chained alias expands once",
+			name:     "chained alias expands fully",
 			aliases:  map[string]string{"A": "<B>", "B": "x"},
 			patterns: []string{"<A>"},
-			input:    "B", // <A> expands to <B> which parses as 'B', so 'B' matches
-			want:     []string{"<A>"},
+			input:    "B", // <B> is an alias, so it never parses as the key 'B'
+			want:     []string{},
 		},
 	}
 
@@ -1727,8 +1748,7 @@ func TestAliases(t *testing.T) {
 	}
 }
 
-func TestAliasExpandsOnce(t *testing.T) {
-	// Ensure aliases only expand once (no recursive expansion)
+func TestAliasExpandsRecursively(t *testing.T) {
 	r := NewRouter()
 	r.SetAlias("A", "<B>")
 	r.SetAlias("B", "x")
@@ -1738,15 +1758,98 @@ func TestAliasExpandsOnce(t *testing.T) {
 		triggered = true
 	})
 
-	// <A> expands to <B>, but <B> does NOT further expand to x
-	// <B> as a key pattern parses as just 'B' since B isn't a special key or modifier
+	// <A> expands to <B>, which further expands to x
 	input := NewInput(r)
-	reader := NewReader(bytes.NewReader([]byte("B")))
+	reader := NewReader(bytes.NewReader([]byte("x")))
 	key, _ := reader.ReadKey()
 	input.Dispatch(key)
 
 	if !triggered {
-		t.Error("<A> should have expanded to <B> which parses as 'B'")
+		t.Error("<A> should have expanded through <B> to x")
+	}
+}
+
+func TestAliasCycleIsLeftUnexpanded(t *testing.T) {
+	r := NewRouter()
+	r.SetAlias("A", "x<B>")
+	r.SetAlias("B", "y<A>")
+
+	if got := r.expandAliases("<A>"); got != "xy<A>" {
+		t.Errorf("expandAliases(<A>) = %q, want %q", got, "xy<A>")
+	}
+
+	want := []Alias{
+		{Name: "a", Expansion: "x<B>", Resolved: "xy<A>", Refs: []string{"b"}, Cycle: true},
+		{Name: "b", Expansion: "y<A>", Resolved: "yx<B>", Refs: []string{"a"}, Cycle: true},
+	}
+	if got := r.Aliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Aliases() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAliasesGraph(t *testing.T) {
+	r := NewRouter().
+		SetAlias("Leader", "<Space>").
+		SetAlias("Git", "<Leader>g")
+
+	want := []Alias{
+		{Name: "git", Expansion: "<Leader>g", Resolved: "<Space>g", Refs: []string{"leader"}},
+		{Name: "leader", Expansion: "<Space>", Resolved: "<Space>"},
+	}
+	if got := r.Aliases(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Aliases() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetAliasReexpandsExistingBindings(t *testing.T) {
+	r := NewRouter().SetAlias("Leader", ",")
+	var named, anon, mounted atomic.Int32
+	r.HandleNamed("find", "<Leader>f", func(m Match) { named.Add(1) })
+	r.Handle("<Leader>b", func(m Match) { anon.Add(1) })
+	sub := NewRouter()
+	sub.Handle("s", func(m Match) { mounted.Add(1) })
+	r.Mount("<Leader>g", sub)
+
+	// changing the alias after registration moves every binding
+	r.SetAlias("Leader", "<Space>")
+
+	in := NewInput(r)
+	for _, k := range ParsePattern(",f,b,gs") {
+		in.Dispatch(k)
+	}
+	if named.Load()+anon.Load()+mounted.Load() != 0 {
+		t.Fatal("old leader should no longer trigger bindings")
+	}
+	for _, k := range ParsePattern("<Space>f<Space>b<Space>gs") {
+		in.Dispatch(k)
+	}
+	if named.Load() != 1 || anon.Load() != 1 || mounted.Load() != 1 {
+		t.Errorf("expected all bindings under the new leader, named=%d anon=%d mounted=%d",
+			named.Load(), anon.Load(), mounted.Load())
+	}
+	if got := r.BindingsMap()["find"]; got != "<Leader>f" {
+		t.Errorf("pattern should stay unexpanded, got %q", got)
+	}
+}
+
+func TestLoadBindingsAliasAffectsDefaults(t *testing.T) {
+	r := NewRouter().SetAlias("Leader", "\\")
+	var hit atomic.Int32
+	r.HandleNamed("find_files", "<Leader>f", func(m Match) { hit.Add(1) })
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	if err := os.WriteFile(path, []byte("[aliases]\nLeader = \",\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatalf("LoadBindingsFrom error: %v", err)
+	}
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: ','})
+	in.Dispatch(Key{Rune: 'f'})
+	if hit.Load() != 1 {
+		t.Error("config Leader should apply to the default <Leader>f binding")
 	}
 }
 
@@ -3321,3 +3424,861 @@ func TestSetRouterClearsAttachedSubs(t *testing.T) {
 		t.Errorf("pane still active after SetRouter: %d", paneHits.Load())
 	}
 }
+
+func TestMountRefusesRoutersSharingBindings(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("a", "a", func(m Match) {})
+	r.Mount("x", r.Clone().Name("c"))
+	r.Mount("y", r.Inherit().Name("v"))
+	r.Mount("z", r.Inherit().Inherit().Name("w"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Rebind("c.a", "xb")
+		r.Rebind("v.a", "yb")
+		r.Reset("c.a")
+		r.Describe("v.a", "")
+		r.UnhandleNamed("w.a")
+		r.Bindings()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("calls through the mounts deadlocked")
+	}
+	if got := r.BindingsMap(); len(got) != 1 || got["a"] != "a" {
+		t.Errorf("BindingsMap() = %v, want only r's own binding", got)
+	}
+}
+
+func TestMountDispatchesThroughPrefix(t *testing.T) {
+	root := NewRouter().SetAlias("Leader", ",")
+	git := NewRouter().Name("git")
+
+	var status, commit atomic.Int32
+	git.HandleNamed("status", "s", func(m Match) { status.Add(1) })
+	git.HandleNamed("commit", "cc", func(m Match) { commit.Add(1) })
+	root.Mount("<Leader>g", git)
+
+	input := NewInput(root)
+	for _, k := range ParsePattern(",gs,gcc") {
+		input.Dispatch(k)
+	}
+	if status.Load() != 1 {
+		t.Errorf("expected git.status to fire once, got %d", status.Load())
+	}
+	if commit.Load() != 1 {
+		t.Errorf("expected git.commit to fire once, got %d", commit.Load())
+	}
+
+	// the bare sub-router pattern must not fire outside the prefix
+	input.Dispatch(Key{Rune: 's'})
+	if status.Load() != 1 {
+		t.Error("sub-router binding should only be reachable through the prefix")
+	}
+}
+
+func TestMountBindingsAreNamespaced(t *testing.T) {
+	root := NewRouter()
+	root.HandleNamed("quit", "q", func(m Match) {})
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	root.Mount("<Space>g", git)
+
+	want := []Binding{
+		{Name: "quit", Pattern: "q", DefaultPattern: "q"},
+		{Name: "git.status", Pattern: "<Space>gs", DefaultPattern: "<Space>gs"},
+	}
+	if got := root.Bindings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Bindings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMountSeesLaterSubRouterChanges(t *testing.T) {
+	root := NewRouter()
+	git := NewRouter().Name("git")
+	root.Mount("g", git)
+
+	var hits atomic.Int32
+	// registered after mounting
+	git.HandleNamed("status", "s", func(m Match) { hits.Add(1) })
+	git.Rebind("status", "S")
+
+	input := NewInput(root)
+	input.Dispatch(Key{Rune: 'g'})
+	input.Dispatch(Key{Rune: 'S'})
+	if hits.Load() != 1 {
+		t.Fatalf("expected rebound sub binding to fire through mount, got %d", hits.Load())
+	}
+
+	if b := root.BindingsMap()["git.status"]; b != "gS" {
+		t.Errorf("expected git.status = gS, got %q", b)
+	}
+}
+
+func TestMountRebindThroughParent(t *testing.T) {
+	root := NewRouter()
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	root.Mount("g", git)
+
+	if !root.Rebind("git.status", "gx") {
+		t.Fatal("expected Rebind through the mount to succeed")
+	}
+	if got := git.BindingsMap()["status"]; got != "x" {
+		t.Errorf("expected sub binding x, got %q", got)
+	}
+	if root.Rebind("git.status", "zx") {
+		t.Error("rebinding outside the mount prefix should fail")
+	}
+
+	if !root.Reset("git.status") {
+		t.Fatal("expected Reset through the mount to succeed")
+	}
+	if got := git.BindingsMap()["status"]; got != "s" {
+		t.Errorf("expected sub binding reset to s, got %q", got)
+	}
+}
+
+func TestMountLoadBindingsDottedKeys(t *testing.T) {
+	root := NewRouter()
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	root.Mount("g", git)
+
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	config := "[myapp]\ngit.status = \"gt\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatalf("LoadBindingsFrom error: %v", err)
+	}
+	if got := root.BindingsMap()["git.status"]; got != "gt" {
+		t.Errorf("expected git.status = gt, got %q", got)
+	}
+}
+
+func TestMountPrefixAmbiguity(t *testing.T) {
+	root := NewRouter().Timeout(20 * time.Millisecond)
+	sub := NewRouter()
+
+	var gHit, subHit atomic.Int32
+	root.Handle("g", func(m Match) { gHit.Add(1) })
+	sub.Handle("x", func(m Match) { subHit.Add(1) })
+	root.Mount("g", sub)
+
+	input := NewInput(root)
+	input.Dispatch(Key{Rune: 'g'})
+	if gHit.Load() != 0 {
+		t.Fatal("g should wait: the mount makes it ambiguous")
+	}
+	input.Dispatch(Key{Rune: 'x'})
+	if subHit.Load() != 1 || gHit.Load() != 0 {
+		t.Errorf("expected mounted gx to win, g=%d sub=%d", gHit.Load(), subHit.Load())
+	}
+
+	input.Dispatch(Key{Rune: 'g'})
+	time.Sleep(50 * time.Millisecond)
+	if gHit.Load() != 1 {
+		t.Errorf("expected g to fire after timeout, got %d", gHit.Load())
+	}
+}
+
+func TestMountHooksNestInsideParent(t *testing.T) {
+	var order []string
+	root := NewRouter()
+	sub := NewRouter()
+	sub.Handle("x", func(m Match) { order = append(order, "handler") })
+	sub.AddOnBefore(func() { order = append(order, "sub-before") })
+	sub.AddOnAfter(func() { order = append(order, "sub-after") })
+	root.AddOnBefore(func() { order = append(order, "root-before") })
+	root.AddOnAfter(func() { order = append(order, "root-after") })
+	root.Mount("m", sub)
+
+	input := NewInput(root)
+	input.Dispatch(Key{Rune: 'm'})
+	input.Dispatch(Key{Rune: 'x'})
+
+	want := []string{"root-before", "sub-before", "handler", "sub-after", "root-after"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("hook order = %v, want %v", order, want)
+	}
+}
+
+func TestCloneSharesBindings(t *testing.T) {
+	normal := NewRouter()
+	normal.HandleNamed("delete", "dd", func(m Match) {})
+	visual := normal.Clone()
+
+	visual.Rebind("delete", "d")
+	if got := normal.BindingsMap()["delete"]; got != "d" {
+		t.Errorf("Clone should share bindings with the original, got %q", got)
+	}
+}
+
+func TestForkDivergesFromParent(t *testing.T) {
+	normal := NewRouter()
+	var normalHits, visualHits atomic.Int32
+	normal.HandleNamed("delete", "dd", func(m Match) { normalHits.Add(1) })
+	normal.SetAlias("Leader", ",")
+
+	visual := normal.Fork()
+	visual.Rebind("delete", "d")
+	visual.Handle("y", func(m Match) { visualHits.Add(1) })
+	visual.SetAlias("Leader", " ")
+
+	if got := normal.BindingsMap()["delete"]; got != "dd" {
+		t.Errorf("fork rebind leaked into parent: %q", got)
+	}
+	if normal.aliases["leader"] != "," {
+		t.Errorf("fork alias leaked into parent: %q", normal.aliases["leader"])
+	}
+
+	in := NewInput(normal)
+	in.Dispatch(Key{Rune: 'y'})
+	in.Dispatch(Key{Rune: 'd'})
+	in.Dispatch(Key{Rune: 'd'})
+	if visualHits.Load() != 0 {
+		t.Error("fork Handle leaked into parent")
+	}
+	if normalHits.Load() != 1 {
+		t.Errorf("expected parent dd to fire once, got %d", normalHits.Load())
+	}
+
+	in = NewInput(visual)
+	in.Dispatch(Key{Rune: 'd'})
+	if normalHits.Load() != 2 {
+		t.Errorf("expected fork d to fire the shared handler, got %d", normalHits.Load())
+	}
+
+	// later changes to the parent do not reach the fork
+	normal.Handle("x", func(m Match) { visualHits.Add(1) })
+	in.Dispatch(Key{Rune: 'x'})
+	if visualHits.Load() != 0 {
+		t.Error("parent Handle after Fork leaked into fork")
+	}
+}
+
+func TestForkForksMountedRouters(t *testing.T) {
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	git.HandleNamed("stage", "a", func(m Match) {})
+	r := NewRouter()
+	r.Mount("g", git)
+
+	f := r.Fork()
+	f.Rebind("git.status", "gx")
+	f.Unbind("git.stage")
+	if got := r.BindingsMap(); got["git.status"] != "gs" || got["git.stage"] != "ga" {
+		t.Errorf("fork changes leaked into the original: %v", got)
+	}
+	if got := f.BindingsMap()["git.status"]; got != "gx" {
+		t.Errorf("fork git.status = %q, want gx", got)
+	}
+
+	r.Rebind("git.status", "gy")
+	f.ResetAll()
+	if got := r.BindingsMap()["git.status"]; got != "gy" {
+		t.Errorf("ResetAll on the fork reset the original: git.status = %q", got)
+	}
+	if got := f.BindingsMap()["git.status"]; got != "gs" {
+		t.Errorf("fork git.status = %q after ResetAll, want gs", got)
+	}
+}
+
+func TestInheritFallsBackToParent(t *testing.T) {
+	normal := NewRouter()
+	var moves, normalDeletes, visualDeletes atomic.Int32
+	normal.HandleNamed("move_down", "j", func(m Match) { moves.Add(1) })
+	normal.HandleNamed("delete", "dd", func(m Match) { normalDeletes.Add(1) })
+
+	visual := normal.Inherit()
+	visual.HandleNamed("delete", "d", func(m Match) { visualDeletes.Add(1) })
+
+	in := NewInput(visual)
+	in.Dispatch(Key{Rune: 'j'})
+	in.Dispatch(Key{Rune: 'd'}) // the parent's dd is shadowed, so d doesn't wait
+	if moves.Load() != 1 {
+		t.Errorf("expected inherited j to fire, got %d", moves.Load())
+	}
+	if visualDeletes.Load() != 1 || normalDeletes.Load() != 0 {
+		t.Errorf("expected child override to shadow parent, visual=%d normal=%d",
+			visualDeletes.Load(), normalDeletes.Load())
+	}
+
+	// later parent changes show through
+	var tops atomic.Int32
+	normal.Handle("gg", func(m Match) { tops.Add(1) })
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 'g'})
+	if tops.Load() != 1 {
+		t.Errorf("expected later parent binding to show through, got %d", tops.Load())
+	}
+
+	// child bindings never touch the parent
+	in = NewInput(normal)
+	in.Dispatch(Key{Rune: 'd'})
+	in.Dispatch(Key{Rune: 'd'})
+	if normalDeletes.Load() != 1 || visualDeletes.Load() != 1 {
+		t.Errorf("expected parent dd unaffected, visual=%d normal=%d",
+			visualDeletes.Load(), normalDeletes.Load())
+	}
+}
+
+func TestInheritShadowedPatternIsNotAmbiguous(t *testing.T) {
+	normal := NewRouter()
+	var deletes, words atomic.Int32
+	normal.HandleNamed("delete", "dd", func(m Match) {})
+	visual := normal.Inherit()
+	visual.HandleNamed("delete", "d", func(m Match) { deletes.Add(1) })
+
+	in := NewInput(visual)
+	in.Dispatch(Key{Rune: 'd'})
+	if _, keys := in.Pending(); len(keys) != 0 || deletes.Load() != 1 {
+		t.Errorf("d should fire at once: pending %v, fired %d", keys, deletes.Load())
+	}
+
+	// a parent binding the child doesn't shadow still makes d wait
+	normal.HandleNamed("delete_word", "dw", func(m Match) { words.Add(1) })
+	in.Dispatch(Key{Rune: 'd'})
+	if _, keys := in.Pending(); len(keys) != 1 {
+		t.Errorf("d should wait for dw: pending %v", keys)
+	}
+	in.Dispatch(Key{Rune: 'w'})
+	if words.Load() != 1 || deletes.Load() != 1 {
+		t.Errorf("dw fired %d, d fired %d", words.Load(), deletes.Load())
+	}
+}
+
+func TestInheritRebindCreatesOverride(t *testing.T) {
+	normal := NewRouter()
+	var hits atomic.Int32
+	normal.HandleNamed("quit", "q", func(m Match) { hits.Add(1) })
+	normal.HandleNamed("move_down", "j", func(m Match) {})
+
+	visual := normal.Inherit()
+	if !visual.Rebind("quit", "Q") {
+		t.Fatal("expected Rebind of inherited binding to succeed")
+	}
+	if got := normal.BindingsMap()["quit"]; got != "q" {
+		t.Errorf("parent binding changed: %q", got)
+	}
+
+	want := []Binding{
+		{Name: "quit", Pattern: "Q", DefaultPattern: "q"},
+		{Name: "move_down", Pattern: "j", DefaultPattern: "j"},
+	}
+	if got := visual.Bindings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Bindings() = %+v, want %+v", got, want)
+	}
+
+	in := NewInput(visual)
+	in.Dispatch(Key{Rune: 'q'})
+	if hits.Load() != 0 {
+		t.Error("overridden parent pattern should be shadowed in the child")
+	}
+	in.Dispatch(Key{Rune: 'Q'})
+	if hits.Load() != 1 {
+		t.Errorf("expected override to fire, got %d", hits.Load())
+	}
+}
+
+func TestInheritUsesParentAliases(t *testing.T) {
+	normal := NewRouter().SetAlias("Leader", ",")
+	var saves, xs atomic.Int32
+	normal.HandleNamed("save", "s", func(m Match) { saves.Add(1) })
+
+	visual := normal.Inherit()
+	visual.HandleNamed("x", "<Leader>x", func(m Match) { xs.Add(1) })
+	if !visual.Rebind("save", "<Leader>s") {
+		t.Fatal("expected Rebind of inherited binding to succeed")
+	}
+	in := NewInput(visual)
+	keys := func(s string) {
+		for _, k := range ParsePattern(s) {
+			in.Dispatch(k)
+		}
+	}
+	keys(",x,s")
+	if xs.Load() != 1 || saves.Load() != 1 {
+		t.Errorf("x fired %d times, save %d; want the parent's <Leader> expanded", xs.Load(), saves.Load())
+	}
+
+	// a parent's alias change rebuilds the child
+	normal.SetAlias("Leader", "<Space>")
+	keys("<Space>x<Space>s")
+	if xs.Load() != 2 || saves.Load() != 2 {
+		t.Errorf("x fired %d times, save %d after the parent's <Leader> changed", xs.Load(), saves.Load())
+	}
+
+	// the child's own alias wins
+	visual.SetAlias("Leader", "\\")
+	keys("\\x")
+	if xs.Load() != 3 {
+		t.Errorf("x fired %d times, want the child's <Leader>", xs.Load())
+	}
+
+	// so do aliases a config loads into the parent
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	if err := os.WriteFile(path, []byte("[aliases]\nLocal = \"m\"\n\n[myapp]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	visual.Rebind("save", "<Local>s")
+	if err := normal.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	keys("ms")
+	if saves.Load() != 3 {
+		t.Errorf("save fired %d times, want <Local> from the parent's config", saves.Load())
+	}
+}
+
+func TestInheritHooksBelongToChild(t *testing.T) {
+	var order []string
+	normal := NewRouter()
+	normal.Handle("j", func(m Match) { order = append(order, "j") })
+	normal.AddOnAfter(func() { order = append(order, "normal-after") })
+
+	visual := normal.Inherit()
+	visual.AddOnAfter(func() { order = append(order, "visual-after") })
+
+	NewInput(visual).Dispatch(Key{Rune: 'j'})
+	want := []string{"j", "visual-after"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestRebindPrunesStalePrefix(t *testing.T) {
+	r := NewRouter().Timeout(time.Hour)
+	var hits atomic.Int32
+	r.HandleNamed("delete", "dd", func(m Match) {})
+	r.Handle("d", func(m Match) { hits.Add(1) })
+	r.Rebind("delete", "x")
+
+	// dd is gone, so d no longer waits for a second key
+	NewInput(r).Dispatch(Key{Rune: 'd'})
+	if hits.Load() != 1 {
+		t.Errorf("expected d to fire immediately after dd was rebound away, got %d", hits.Load())
+	}
+}
+
+func TestRebindKeepsHandlerRegisteredOverIt(t *testing.T) {
+	r := NewRouter()
+	var named, anon atomic.Int32
+	r.HandleNamed("down", "j", func(m Match) { named.Add(1) })
+	r.Handle("j", func(m Match) { anon.Add(1) })
+	r.Rebind("down", "n")
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'j'})
+	in.Dispatch(Key{Rune: 'n'})
+	if anon.Load() != 1 || named.Load() != 1 {
+		t.Errorf("expected both handlers to survive the rebind, anon=%d named=%d", anon.Load(), named.Load())
+	}
+}
+
+func TestUnhandle(t *testing.T) {
+	r := NewRouter().Timeout(time.Hour)
+	var g, gg atomic.Int32
+	r.Handle("g", func(m Match) { g.Add(1) })
+	r.Handle("gg", func(m Match) { gg.Add(1) })
+
+	if !r.Unhandle("gg") {
+		t.Fatal("expected Unhandle to report a removal")
+	}
+	if r.Unhandle("gg") {
+		t.Error("second Unhandle should report nothing removed")
+	}
+	if r.Unhandle("zz") {
+		t.Error("Unhandle of an unknown pattern should report nothing removed")
+	}
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: 'g'})
+	if g.Load() != 1 {
+		t.Errorf("expected g to fire without waiting, got %d", g.Load())
+	}
+	if _, keys := in.Pending(); len(keys) != 0 {
+		t.Errorf("expected empty buffer, got %v", keys)
+	}
+}
+
+func TestUnhandleNamed(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) { t.Error("removed binding fired") })
+	r.HandleNamed("down", "j", func(m Match) {})
+
+	if !r.UnhandleNamed("quit") {
+		t.Fatal("expected UnhandleNamed to succeed")
+	}
+	if r.UnhandleNamed("quit") {
+		t.Error("second UnhandleNamed should fail")
+	}
+	want := []Binding{{Name: "down", Pattern: "j", DefaultPattern: "j"}}
+	if got := r.Bindings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Bindings() = %+v, want %+v", got, want)
+	}
+	if r.Unhandle("q") {
+		t.Error("pattern should already be gone")
+	}
+	NewInput(r).Dispatch(Key{Rune: 'q'})
+
+	// Unhandle on a named pattern removes the binding as well
+	if !r.Unhandle("j") {
+		t.Fatal("expected Unhandle of a named pattern to succeed")
+	}
+	if len(r.Bindings()) != 0 {
+		t.Errorf("expected no bindings, got %+v", r.Bindings())
+	}
+}
+
+func TestUnbindInherited(t *testing.T) {
+	normal := NewRouter()
+	normal.HandleNamed("delete_line", "dd", func(m Match) { t.Error("unbound binding fired") })
+
+	safe := normal.Inherit()
+	if !safe.Unbind("delete_line") {
+		t.Fatal("expected Unbind of inherited binding to succeed")
+	}
+	want := []Binding{{Name: "delete_line", Pattern: "", DefaultPattern: "dd"}}
+	if got := safe.Bindings(); !reflect.DeepEqual(got, want) || !got[0].Unbound() {
+		t.Errorf("Bindings() = %+v, want %+v", got, want)
+	}
+	in := NewInput(safe)
+	in.Dispatch(Key{Rune: 'd'})
+	in.Dispatch(Key{Rune: 'd'})
+	in.Flush()
+
+	if normal.Bindings()[0].Unbound() {
+		t.Error("parent binding should stay bound")
+	}
+}
+
+func TestUnhandleRecomputesEscapeSequences(t *testing.T) {
+	r := NewRouter()
+	r.Handle("<Up>", func(m Match) {})
+	r.Handle("j", func(m Match) {})
+	if !r.HasEscapeSequences() {
+		t.Fatal("expected escape sequences with <Up> registered")
+	}
+	r.Unhandle("<Up>")
+	if r.HasEscapeSequences() {
+		t.Error("expected no escape sequences after removing <Up>")
+	}
+
+	r.HandleNamed("left", "<Left>", func(m Match) {})
+	r.Rebind("left", "h")
+	if r.HasEscapeSequences() {
+		t.Error("expected no escape sequences after rebinding <Left> away")
+	}
+}
+
+func TestRebindWhileDispatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[aliases]\nLeader = \";\"\n\n[myapp]\nsave = \"<Leader>w\"\ngit.status = \"gS\"\n")
+
+	base := NewRouter()
+	base.HandleNamed("quit", "q", func(m Match) {})
+	r := base.Inherit()
+	r.HandleNamed("save", "<Leader>s", func(m Match) {})
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	r.Mount("g", git)
+
+	input := NewInput(r)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		keys := []Key{{Rune: 'q'}, {Rune: ','}, {Rune: 's'}, {Rune: 'g'}, {Rune: 's'}, {Rune: ';'}, {Rune: 'w'}, {Special: SpecialUp}}
+		for n := 0; ; n++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			input.Dispatch(keys[n%len(keys)])
+		}
+	}()
+
+	for n := range 200 {
+		r.SetAlias("Leader", string(rune('a'+n%3)))
+		r.Rebind("save", "<C-s>")
+		r.Rebind("quit", "Q")
+		r.Rebind("git.status", "gs")
+		base.Rebind("quit", "x")
+		git.Rebind("status", "t")
+		r.Handle("<Up>", func(m Match) {})
+		r.Unhandle("<Up>")
+		if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+			t.Fatal(err)
+		}
+		r.ResetAll()
+		base.ResetAll()
+		_ = r.Bindings()
+		_ = r.HasEscapeSequences()
+	}
+	close(stop)
+	wg.Wait()
+
+	if got := r.BindingsMap()["save"]; got != "<Leader>s" {
+		t.Errorf("save = %q after ResetAll, want <Leader>s", got)
+	}
+}
+
+func TestParsePatternLiteralCharacters(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []Key
+	}{
+		{"<lt>", []Key{{Rune: '<'}}},
+		{"<gt>", []Key{{Rune: '>'}}},
+		{"<Bslash>", []Key{{Rune: '\\'}}},
+		{"<Bar>", []Key{{Rune: '|'}}},
+		{"<DQuote>", []Key{{Rune: '"'}}},
+		{"<Minus>", []Key{{Rune: '-'}}},
+		{"<lt><lt>", []Key{{Rune: '<'}, {Rune: '<'}}},
+		{"<C-lt>", []Key{{Rune: '<', Mod: ModCtrl}}},
+		{"<A-gt>", []Key{{Rune: '>', Mod: ModAlt}}},
+		{"<C-->", []Key{{Rune: '-', Mod: ModCtrl}}},
+
+		// spans that aren't key notation are literal, as in vim
+		{"<>", []Key{{Rune: '<'}, {Rune: '>'}}},
+		{"<<", []Key{{Rune: '<'}, {Rune: '<'}}},
+		{">>", []Key{{Rune: '>'}, {Rune: '>'}}},
+		{"<x>", []Key{{Rune: '<'}, {Rune: 'x'}, {Rune: '>'}}},
+		{"<<C-w>", []Key{{Rune: '<'}, {Rune: 'w', Mod: ModCtrl}}},
+		{"<foo>", []Key{{Rune: '<'}, {Rune: 'f'}, {Rune: 'o'}, {Rune: 'o'}, {Rune: '>'}}},
+		{"<C->>", []Key{{Rune: '<'}, {Rune: 'C'}, {Rune: '-'}, {Rune: '>'}, {Rune: '>'}}},
+
+		// modified multi-byte characters
+		{"<A-é>", []Key{{Rune: 'é', Mod: ModAlt}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got := ParsePattern(tt.pattern)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePattern(%q) = %v, want %v", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPattern(t *testing.T) {
+	tests := []struct {
+		keys []Key
+		want string
+	}{
+		{[]Key{{Rune: 'g'}, {Rune: 'g'}}, "gg"},
+		{[]Key{{Rune: '<'}, {Rune: '<'}}, "<lt><lt>"},
+		{[]Key{{Rune: '>'}, {Rune: '>'}}, "<gt><gt>"},
+		{[]Key{{Rune: '|'}, {Rune: '\\'}, {Rune: '"'}}, "<Bar><Bslash><DQuote>"},
+		{[]Key{{Rune: 'w', Mod: ModCtrl}, {Rune: 'j'}}, "<C-w>j"},
+		{[]Key{{Special: SpecialTab, Mod: ModShift}}, "<S-Tab>"},
+		{[]Key{{Rune: '<', Mod: ModCtrl | ModAlt}}, "<C-A-lt>"},
+		{[]Key{{Rune: '-', Mod: ModCtrl}}, "<C-->"},
+		{[]Key{{Paste: "ignored"}, {}}, ""},
+	}
+	for _, tt := range tests {
+		if got := FormatPattern(tt.keys); got != tt.want {
+			t.Errorf("FormatPattern(%v) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestFormatPatternRoundTrip(t *testing.T) {
+	mods := []Modifier{
+		ModNone, ModCtrl, ModAlt, ModShift, ModSuper,
+		ModCtrl | ModAlt, ModCtrl | ModShift, ModAlt | ModShift, ModCtrl | ModAlt | ModShift,
+		ModCtrl | ModSuper, ModCtrl | ModAlt | ModShift | ModSuper,
+	}
+	var keys []Key
+	for _, mod := range mods {
+		for s := SpecialEscape; s <= SpecialF12; s++ {
+			keys = append(keys, Key{Special: s, Mod: mod})
+		}
+		for r := rune(' '); r <= '~'; r++ {
+			keys = append(keys, Key{Rune: r, Mod: mod})
+		}
+		for _, r := range "£éλ日🙂" {
+			keys = append(keys, Key{Rune: r, Mod: mod})
+		}
+	}
+
+	for _, k := range keys {
+		pattern := FormatPattern([]Key{k})
+		if got := ParsePattern(pattern); !reflect.DeepEqual(got, []Key{k}) {
+			t.Errorf("ParsePattern(FormatPattern(%#v)) via %q = %v", k, pattern, got)
+		}
+	}
+
+	// and as one long sequence
+	if got := ParsePattern(FormatPattern(keys)); !reflect.DeepEqual(got, keys) {
+		t.Error("sequence did not round-trip")
+	}
+}
+
+func TestLiteralBracketBindings(t *testing.T) {
+	r := NewRouter()
+	var shiftLeft, shiftRight atomic.Int32
+	r.Handle("<lt><lt>", func(m Match) { shiftLeft.Add(1) })
+	r.Handle(">>", func(m Match) { shiftRight.Add(1) })
+
+	in := NewInput(r)
+	for _, b := range []byte("<<>>") {
+		in.Dispatch(Key{Rune: rune(b)})
+	}
+	if shiftLeft.Load() != 1 || shiftRight.Load() != 1 {
+		t.Errorf("expected << and >> to fire once each, got %d and %d", shiftLeft.Load(), shiftRight.Load())
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		in, want Key
+	}{
+		{Key{Rune: 'W', Mod: ModCtrl}, Key{Rune: 'w', Mod: ModCtrl}},
+		{Key{Rune: 'w', Mod: ModCtrl}, Key{Rune: 'w', Mod: ModCtrl}},
+		{Key{Rune: 'W', Mod: ModCtrl | ModShift}, Key{Rune: 'w', Mod: ModCtrl | ModShift}},
+		{Key{Rune: 'a', Mod: ModShift}, Key{Rune: 'A'}},
+		{Key{Rune: 'A', Mod: ModShift}, Key{Rune: 'A'}},
+		{Key{Rune: 'é', Mod: ModShift}, Key{Rune: 'É'}},
+		{Key{Rune: 'x', Mod: ModAlt | ModShift}, Key{Rune: 'X', Mod: ModAlt}},
+		{Key{Rune: 'X', Mod: ModAlt}, Key{Rune: 'X', Mod: ModAlt}},
+		{Key{Rune: '1', Mod: ModShift}, Key{Rune: '1', Mod: ModShift}}, // needs a layout
+		{Key{Special: SpecialTab, Mod: ModShift}, Key{Special: SpecialTab, Mod: ModShift}},
+		{Key{Rune: 'j'}, Key{Rune: 'j'}},
+		{Key{Rune: ' '}, Key{Special: SpecialSpace}},
+		{Key{Rune: ' ', Mod: ModCtrl}, Key{Special: SpecialSpace, Mod: ModCtrl}},
+		{Key{Rune: 'H', Mod: ModCtrl}, Key{Special: SpecialBackspace}},
+		{Key{Rune: 'i', Mod: ModCtrl | ModAlt}, Key{Special: SpecialTab, Mod: ModAlt}},
+		{Key{Rune: 'm', Mod: ModCtrl}, Key{Special: SpecialEnter}},
+		{Key{Rune: '[', Mod: ModCtrl}, Key{Special: SpecialEscape}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeKey(tt.in); got != tt.want {
+			t.Errorf("NormalizeKey(%#v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLayoutNormalize(t *testing.T) {
+	tests := []struct {
+		in, want Key
+	}{
+		{Key{Rune: '1', Mod: ModShift}, Key{Rune: '!'}},
+		{Key{Rune: ';', Mod: ModShift}, Key{Rune: ':'}},
+		{Key{Rune: '1', Mod: ModShift | ModAlt}, Key{Rune: '!', Mod: ModAlt}},
+		{Key{Rune: '1', Mod: ModShift | ModCtrl}, Key{Rune: '1', Mod: ModShift | ModCtrl}},
+		{Key{Rune: 'a', Mod: ModShift}, Key{Rune: 'A'}},
+	}
+	for _, tt := range tests {
+		if got := LayoutUS.Normalize(tt.in); got != tt.want {
+			t.Errorf("LayoutUS.Normalize(%#v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+	if got := Layout(nil).Normalize(Key{Rune: '1', Mod: ModShift}); got != (Key{Rune: '1', Mod: ModShift}) {
+		t.Errorf("nil layout should only apply NormalizeKey, got %#v", got)
+	}
+}
+
+func TestPatternSpellingsMatchReader(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   []byte
+	}{
+		{"<C-W>", []byte{23}},
+		{"<C-w>", []byte{23}},
+		{"<S-a>", []byte("A")},
+		{"<s-g><S-g>", []byte("GG")},
+		{"<A-S-x>", []byte{27, 'X'}},
+		{" ", []byte(" ")},
+		{"<Space>", []byte(" ")},
+		{"<C-Space>", []byte{0}},
+		{"<A-Space>", []byte{27, ' '}},
+		{"<C-h>", []byte{8}},
+		{"<C-i>", []byte{9}},
+		{"<C-m>", []byte{13}},
+		{"<C-[>", []byte{27}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			r := NewRouter()
+			var hit atomic.Bool
+			r.Handle(tt.pattern, func(m Match) { hit.Store(true) })
+			in := NewInput(r)
+			reader := NewReader(bytes.NewReader(tt.input))
+			for {
+				k, err := reader.ReadKey()
+				if err != nil {
+					break
+				}
+				in.Dispatch(k)
+			}
+			if !hit.Load() {
+				t.Errorf("pattern %q did not match input %q", tt.pattern, tt.input)
+			}
+		})
+	}
+}
+
+func TestEmacsCtrlSpaceMatchesReader(t *testing.T) {
+	r := NewRouter(WithNotation(NotationEmacs))
+	hits := 0
+	r.Handle("C-SPC", func(m Match) { hits++ })
+	r.Handle("SPC", func(m Match) { hits++ })
+	in := NewInput(r)
+	reader := NewReader(bytes.NewReader([]byte{0, ' '}))
+	for {
+		k, err := reader.ReadKey()
+		if err != nil {
+			break
+		}
+		in.Dispatch(k)
+	}
+	if hits != 2 {
+		t.Errorf("C-SPC and SPC fired %d times, want 2", hits)
+	}
+}
+
+func TestDispatchNormalisesKeys(t *testing.T) {
+	r := NewRouter()
+	var got Match
+	r.Handle("<C-w>", func(m Match) { got = m })
+
+	NewInput(r).Dispatch(Key{Rune: 'W', Mod: ModCtrl})
+	if want := []Key{{Rune: 'w', Mod: ModCtrl}}; !reflect.DeepEqual(got.Keys, want) {
+		t.Errorf("Match.Keys = %v, want %v", got.Keys, want)
+	}
+}
+
+func TestRouterLayout(t *testing.T) {
+	r := NewRouter()
+	var hits atomic.Int32
+	r.Handle("<S-1>", func(m Match) { hits.Add(1) })
+
+	in := NewInput(r)
+	in.Dispatch(Key{Rune: '!'})
+	if hits.Load() != 0 {
+		t.Fatal("without a layout <S-1> should not match !")
+	}
+
+	r.SetLayout(LayoutUS)
+	in.Dispatch(Key{Rune: '!'})
+	in.Dispatch(Key{Rune: '1', Mod: ModShift})
+	if hits.Load() != 2 {
+		t.Errorf("expected <S-1> to match both spellings of !, got %d", hits.Load())
+	}
+
+	opt := NewRouter(WithLayout(LayoutUS))
+	opt.Handle("<S-/>", func(m Match) { hits.Add(1) })
+	NewInput(opt).Dispatch(Key{Rune: '?'})
+	if hits.Load() != 3 {
+		t.Errorf("expected WithLayout router to match ?, got %d", hits.Load())
+	}
+}
@@ -0,0 +1,389 @@
+package riffkey
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SaveBindings writes the router's rebound bindings to the user config file,
+// ConfigPath(), as SaveBindingsTo does. The sources ConfigPaths lists before
+// the user file, such as /etc/xdg/riffkey.toml, count as earlier sections
+// too: a binding one of them rebinds is written even at its default, so
+// resetting it in the app outlasts the next load.
+func (r *Router) SaveBindings(appName string, modes ...*Router) error {
+	user := ConfigPath()
+	paths := ConfigPaths()
+	lower := paths[:max(slices.Index(paths, user), 0)]
+	return r.saveBindings(user, lower, appName, modes)
+}
+
+// SaveBindingsTo updates the app's section of the config file at path with
+// the bindings that differ from their defaults, for in-app keybinding
+// editors. Entries are changed in place; entries for bindings back at their
+// default are removed; other sections, unknown keys and comments are left
+// as they are. Mode routers are saved to their [<app>.<name>] sections, as
+// WriteDefaultBindings lays them out.
+//
+// A binding back at its default is still written if another section that
+// LoadBindings applies to the router first ([global], or for a named r also
+// [<app>] and [global.<name>]) rebinds it, so that entry doesn't take over on
+// the next load. The same goes for any section of a file the config includes.
+// Sources LoadBindings reads before path but path doesn't include are not
+// consulted; SaveBindings consults those of ConfigPaths.
+//
+// Patterns are written in the notation the file declares with a top-level
+// notation key, converted from the router's as LoadBindings converts them
+// back; a file without one gets the router's own.
+//
+// The file is replaced atomically via a temporary file and rename, so a
+// crash leaves either the old or the new config. A file that doesn't parse
+// is not touched.
+func (r *Router) SaveBindingsTo(path, appName string, modes ...*Router) error {
+	return r.saveBindings(path, nil, appName, modes)
+}
+
+// saveBindings implements SaveBindingsTo, with the config sources applied
+// before path, lowest precedence first, in lower.
+func (r *Router) saveBindings(path string, lower []string, appName string, modes []*Router) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var raw map[string]any
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return err
+	}
+	from, declared := NotationVim, false
+	if name, ok := raw["notation"].(string); ok {
+		from, declared = ParseNotation(name)
+	}
+	// every section of the files applied before path comes earlier,
+	// path's own includes among them
+	files, err := readConfigs(append(slices.Clone(lower), path))
+	if err != nil {
+		return err
+	}
+	self, _ := filepath.Abs(path)
+	files = slices.DeleteFunc(files, func(f configFile) bool { return f.path == self })
+
+	var targets []saveTarget
+	seen := make(map[string]bool)
+	for i, m := range append([]*Router{r}, modes...) {
+		// the sections LoadBindings applies to m before the one saved to
+		section := appName
+		var earlier []string
+		if i == 0 {
+			earlier = append(earlier, "global")
+		}
+		switch {
+		case len(modes) > 0 && m.name != "":
+			if i == 0 {
+				earlier = append(earlier, appName)
+			}
+			earlier = append(earlier, "global."+m.name)
+			section += "." + m.name
+		case i > 0:
+			continue // an unnamed mode has no section
+		}
+		if seen[section] {
+			continue
+		}
+		seen[section] = true
+		var overridden []map[string]any
+		for _, e := range earlier {
+			overridden = append(overridden, getNestedSection(raw, e))
+		}
+		for _, f := range files {
+			for _, e := range append(earlier, section) {
+				overridden = append(overridden, getNestedSection(f.raw, e))
+			}
+		}
+		t := newSaveTarget(section, m, overridden)
+		if declared && from != m.notation {
+			for name, p := range t.want {
+				if p != "" {
+					t.want[name] = from.Format(m.notation.Parse(m.expanded(p)))
+				}
+			}
+		}
+		targets = append(targets, t)
+	}
+
+	out := updateSections(string(data), targets)
+	var check map[string]any
+	if _, err := toml.Decode(out, &check); err != nil {
+		return err // never replace a config with one that doesn't load
+	}
+	return writeFileAtomic(path, []byte(out))
+}
+
+// saveTarget is a config section and the bindings to write to it.
+type saveTarget struct {
+	section []string
+	known   map[string]bool   // every binding name of the router
+	want    map[string]string // name -> pattern to write
+	order   []string          // names in want, in binding order
+}
+
+// newSaveTarget collects r's bindings that differ from their defaults, or
+// that one of the earlier sections sets and section must override.
+func newSaveTarget(section string, r *Router, earlier []map[string]any) saveTarget {
+	t := saveTarget{
+		section: strings.Split(section, "."),
+		known:   make(map[string]bool),
+		want:    make(map[string]string),
+	}
+	for _, b := range r.Bindings() {
+		t.known[b.Name] = true
+		if b.Pattern != b.DefaultPattern || slices.ContainsFunc(earlier, func(e map[string]any) bool { return sectionSets(e, b.Name) }) {
+			t.want[b.Name] = b.Pattern
+			t.order = append(t.order, b.Name)
+		}
+	}
+	return t
+}
+
+// sectionSets reports whether a config section has an entry for name,
+// following dotted names into nested tables.
+func sectionSets(section map[string]any, name string) bool {
+	if section == nil {
+		return false
+	}
+	switch section[name].(type) {
+	case string, bool:
+		return true
+	}
+	first, rest, ok := strings.Cut(name, ".")
+	if !ok {
+		return false
+	}
+	nested, _ := section[first].(map[string]any)
+	return sectionSets(nested, rest)
+}
+
+// updateSections rewrites doc so that each target's section holds exactly
+// its wanted bindings, leaving everything else untouched.
+func updateSections(doc string, targets []saveTarget) string {
+	if doc == "" {
+		doc = "\n"
+	}
+	if !strings.HasSuffix(doc, "\n") {
+		doc += "\n"
+	}
+	lines := tomlLines(strings.TrimSuffix(doc, "\n"))
+	text := make([]string, len(lines))
+	keep := make([]bool, len(lines))
+	after := make(map[int][]string) // line index -> lines to insert after it
+	for i, l := range lines {
+		text[i], keep[i] = l.text, true
+	}
+
+	// table (joined) -> index of its last non-blank line, for each table
+	// that belongs to a target
+	type table struct {
+		target int
+		prefix string // binding-name prefix the table adds, e.g. "git."
+		last   int
+	}
+	tables := make(map[string]*table)
+	var tableOrder []string
+	written := make([]map[string]bool, len(targets))
+	for i := range written {
+		written[i] = make(map[string]bool)
+	}
+
+	var cur *table
+	for i, l := range lines {
+		if l.header {
+			cur = nil
+			if t, prefix, ok := ownerOf(l.table, targets); ok {
+				name := strings.Join(l.table, ".")
+				if tables[name] == nil {
+					tables[name] = &table{target: t, prefix: prefix}
+					tableOrder = append(tableOrder, name)
+				}
+				cur = tables[name]
+				cur.last = i
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if strings.TrimSpace(l.text) != "" {
+			cur.last = i
+		}
+		if l.key == nil {
+			continue
+		}
+		t := targets[cur.target]
+		name := cur.prefix + strings.Join(l.key, ".")
+		if !t.known[name] {
+			continue // not a binding; leave it alone
+		}
+		pattern, ok := t.want[name]
+		if !ok || written[cur.target][name] {
+			keep[i] = false
+			continue
+		}
+		written[cur.target][name] = true
+		text[i] = l.text[:l.value] + " " + quoteToml(pattern) + trailingComment(l.text[l.value:])
+	}
+
+	var appended []string
+	for ti, t := range targets {
+		for _, name := range t.order {
+			if written[ti][name] {
+				continue
+			}
+			// add to the table that gives the longest matching prefix
+			var best *table
+			for _, tn := range tableOrder {
+				tb := tables[tn]
+				if tb.target == ti && strings.HasPrefix(name, tb.prefix) && (best == nil || len(tb.prefix) > len(best.prefix)) {
+					best = tb
+				}
+			}
+			if best == nil {
+				best = &table{target: ti, last: -1}
+				tables[strings.Join(t.section, ".")] = best
+				tableOrder = append(tableOrder, strings.Join(t.section, "."))
+				appended = append(appended, "", "["+formatKeyPath(t.section)+"]")
+			}
+			entry := formatKeyPath(strings.Split(name[len(best.prefix):], ".")) + " = " + quoteToml(t.want[name])
+			if best.last == -1 {
+				appended = append(appended, entry)
+			} else {
+				after[best.last] = append(after[best.last], entry)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i := range lines {
+		if keep[i] {
+			sb.WriteString(text[i] + "\n")
+		}
+		for _, entry := range after[i] {
+			sb.WriteString(entry + "\n")
+		}
+	}
+	out := sb.String()
+	if len(appended) > 0 {
+		if strings.TrimSpace(out) == "" {
+			out = ""
+			appended = appended[1:] // no blank line at the top of a new file
+		}
+		out += strings.Join(appended, "\n") + "\n"
+	}
+	return out
+}
+
+// ownerOf finds the target a table belongs to: the one whose section is the
+// longest prefix of the table's path. The rest of the path becomes a prefix
+// for binding names, as for mounted sub-routers.
+func ownerOf(path []string, targets []saveTarget) (int, string, bool) {
+	best := -1
+	for i, t := range targets {
+		if len(path) >= len(t.section) && slices.Equal(path[:len(t.section)], t.section) {
+			if best == -1 || len(t.section) > len(targets[best].section) {
+				best = i
+			}
+		}
+	}
+	if best == -1 {
+		return 0, "", false
+	}
+	prefix := ""
+	if rest := path[len(targets[best].section):]; len(rest) > 0 {
+		prefix = strings.Join(rest, ".") + "."
+	}
+	return best, prefix, true
+}
+
+// trailingComment returns the comment after a single-line string value, with
+// the whitespace before it, or "".
+func trailingComment(value string) string {
+	value = strings.TrimLeft(value, " \t")
+	if value == "" {
+		return ""
+	}
+	end := -1
+	switch value[0] {
+	case '"':
+		for i := 1; i < len(value); i++ {
+			if value[i] == '\\' {
+				i++
+			} else if value[i] == '"' {
+				end = i + 1
+				break
+			}
+		}
+	case '\'':
+		if i := strings.IndexByte(value[1:], '\''); i != -1 {
+			end = i + 2
+		}
+	}
+	if end == -1 {
+		return ""
+	}
+	rest := value[end:]
+	if strings.HasPrefix(strings.TrimLeft(rest, " \t"), "#") {
+		return rest
+	}
+	return ""
+}
+
+func quoteToml(s string) string {
+	return `"` + escapeTomlString(s) + `"`
+}
+
+// formatKeyPath writes a dotted TOML key, quoting parts that aren't bare.
+func formatKeyPath(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, p := range parts {
+		quoted[i] = p
+		if p == "" || strings.IndexFunc(p, func(r rune) bool { return r > 0x7f || !isBareKeyChar(byte(r)) }) != -1 {
+			quoted[i] = quoteToml(p)
+		}
+	}
+	return strings.Join(quoted, ".")
+}
+
+// writeFileAtomic replaces the file at path with data via a temporary file
+// in the same directory and a rename, keeping the old file's permissions.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,274 @@
+package riffkey
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveBindingsTo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, `# shared keys
+[global]
+help = "?"
+
+[myapp]
+# my quit key
+quit = "Q" # capital
+save = "S"
+theme = "dark"
+
+[other]
+quit = "x"
+`)
+
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+	r.HandleNamed("down", "j", func(m Match) {})
+	if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Rebind("quit", "<C-q>")
+	r.Reset("save")
+	r.Rebind("down", "<Down>")
+	if err := r.SaveBindingsTo(path, "myapp"); err != nil {
+		t.Fatalf("SaveBindingsTo error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# shared keys
+[global]
+help = "?"
+
+[myapp]
+# my quit key
+quit = "<C-q>" # capital
+theme = "dark"
+down = "<Down>"
+
+[other]
+quit = "x"
+`
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+
+	// the saved file loads back to the same bindings
+	loaded := NewRouter()
+	loaded.HandleNamed("quit", "q", func(m Match) {})
+	loaded.HandleNamed("save", "s", func(m Match) {})
+	loaded.HandleNamed("down", "j", func(m Match) {})
+	if err := loaded.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	got, exp := loaded.BindingsMap(), r.BindingsMap()
+	for name := range exp {
+		if got[name] != exp[name] {
+			t.Errorf("%s = %q after reload, want %q", name, got[name], exp[name])
+		}
+	}
+}
+
+func TestSaveBindingsToNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "riffkey.toml")
+
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+	normal := NewRouter().Name("normal")
+	normal.HandleNamed("down", "j", func(m Match) {})
+	r.Rebind("quit", "Q")
+	normal.Rebind("down", "<Down>")
+
+	if err := r.SaveBindingsTo(path, "myapp", normal); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[myapp]
+quit = "Q"
+
+[myapp.normal]
+down = "<Down>"
+`
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestSaveBindingsToKeepsGlobalOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[global]\nquit = \"Q\"\n")
+
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	if err := r.SaveBindingsTo(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewRouter()
+	loaded.HandleNamed("quit", "q", func(m Match) {})
+	if err := loaded.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.BindingsMap()["quit"]; got != "q" {
+		t.Errorf("quit = %q, the saved default should win over [global]", got)
+	}
+}
+
+func TestSaveBindingsToNamedRouterOverridesEarlierSections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[global]\nsave = \"S\"\n\n[global.normal]\nfind = \"F\"\n\n[myapp]\nquit = \"Q\"\n")
+
+	newRouters := func() (*Router, *Router) {
+		normal := NewRouter().Name("normal")
+		normal.HandleNamed("quit", "q", func(m Match) {})
+		normal.HandleNamed("save", "s", func(m Match) {})
+		normal.HandleNamed("find", "f", func(m Match) {})
+		return normal, NewRouter().Name("insert")
+	}
+	normal, insert := newRouters()
+	if err := normal.LoadBindingsFrom(path, "myapp", insert); err != nil {
+		t.Fatal(err)
+	}
+	normal.ResetAll()
+	if err := normal.SaveBindingsTo(path, "myapp", insert); err != nil {
+		t.Fatal(err)
+	}
+
+	normal, insert = newRouters()
+	if err := normal.LoadBindingsFrom(path, "myapp", insert); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"quit": "q", "save": "s", "find": "f"}
+	if got := normal.BindingsMap(); !maps.Equal(got, want) {
+		t.Errorf("bindings after reload = %v, want the defaults %v", got, want)
+	}
+}
+
+func TestSaveBindingsToFileNotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "notation = \"emacs\"\n\n[myapp]\nsave = \"C-x C-s\"\n")
+
+	newRouter := func() *Router {
+		r := NewRouter()
+		r.HandleNamed("quit", "q", func(m Match) {})
+		r.HandleNamed("save", "s", func(m Match) {})
+		return r
+	}
+	r := newRouter()
+	if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	r.Rebind("quit", "<C-q>")
+	if err := r.SaveBindingsTo(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "notation = \"emacs\"\n\n[myapp]\nsave = \"C-x C-s\"\nquit = \"C-q\"\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+
+	loaded := newRouter()
+	if err := loaded.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.BindingsMap(); !maps.Equal(got, r.BindingsMap()) {
+		t.Errorf("bindings after reload = %v, want %v", got, r.BindingsMap())
+	}
+}
+
+func TestSaveBindingsOverridesLowerSources(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(dir, "etc"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "home"))
+	t.Setenv("RIFFKEY_CONFIG", "")
+	t.Chdir(dir)
+	for _, d := range []string{"etc", "home"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(t, filepath.Join(dir, "etc", "riffkey.toml"), "[myapp]\nquit = \"Q\"\n")
+	writeConfig(t, filepath.Join(dir, "home", "base.toml"), "[myapp]\nsave = \"S\"\n")
+	writeConfig(t, ConfigPath(), "include = \"base.toml\"\n")
+
+	newRouter := func() *Router {
+		r := NewRouter()
+		r.HandleNamed("quit", "q", func(m Match) {})
+		r.HandleNamed("save", "s", func(m Match) {})
+		return r
+	}
+	r := newRouter()
+	if err := r.LoadBindings("myapp"); err != nil {
+		t.Fatal(err)
+	}
+	r.ResetAll()
+	if err := r.SaveBindings("myapp"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newRouter()
+	if err := loaded.LoadBindings("myapp"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"quit": "q", "save": "s"}
+	if got := loaded.BindingsMap(); !maps.Equal(got, want) {
+		t.Errorf("bindings after reload = %v, want the defaults %v", got, want)
+	}
+}
+
+func TestSaveBindingsToNestedTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp.git]\nstatus = \"<Space>gS\"\n")
+
+	r := NewRouter()
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	git.HandleNamed("commit", "c", func(m Match) {})
+	r.Mount("<Space>g", git)
+	if err := r.LoadBindingsFrom(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+	r.Rebind("git.commit", "<Space>gC")
+	if err := r.SaveBindingsTo(path, "myapp"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[myapp.git]\nstatus = \"<Space>gS\"\ncommit = \"<Space>gC\"\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestSaveBindingsToRejectsMalformedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp\n")
+
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.Rebind("quit", "Q")
+	if err := r.SaveBindingsTo(path, "myapp"); err == nil {
+		t.Fatal("expected an error for a malformed file")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "[myapp\n" {
+		t.Errorf("malformed file was changed: %q", data)
+	}
+}
@@ -0,0 +1,228 @@
+package riffkey
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// WriteSchema writes a JSON Schema for riffkey.toml describing appName's
+// section, for editor completion and validation through taplo or another
+// TOML language server. Each named binding becomes a property with its
+// description and default pattern; mode routers (as for LoadBindings) get
+// their [<app>.<name>] and [global.<name>] tables. Patterns in vim notation
+// are checked against a regex of valid keys: those of a file with notation =
+// "vim", or of vim-notation routers in a file that doesn't set a notation.
+//
+// Sections of other apps are allowed but not described, so one schema per
+// app can be used with a shared config file.
+//
+// Example, with a taplo directive at the top of riffkey.toml:
+//
+//	#:schema ./riffkey.schema.json
+func (r *Router) WriteSchema(w io.Writer, appName string, modes ...*Router) error {
+	routers := []*Router{r}
+	for _, m := range modes {
+		if m.name != "" && !slices.Contains(routers, m) {
+			routers = append(routers, m)
+		}
+	}
+
+	app, global := schemaSections(routers, true, schemaBinding)
+	app["description"] = "Bindings for " + appName + "; they override [global]."
+	global["description"] = "Bindings shared by every app."
+
+	properties := map[string]any{
+		"notation": map[string]any{
+			"description": "Notation of the patterns in this file.",
+			"enum":        []string{"vim", "emacs", "vscode"},
+		},
+		"include": map[string]any{
+			"description": "Config files applied before this one, relative to it.",
+			"anyOf": []any{
+				map[string]any{"type": "string"},
+				map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			},
+		},
+		"aliases": map[string]any{
+			"description":          "Pattern aliases, used as <Name> in bindings.",
+			"type":                 "object",
+			"additionalProperties": map[string]any{"type": "string"},
+		},
+		"global": global,
+	}
+	properties[appSection(appName)] = nestApp(appName, app)
+
+	// the vim regex depends on the notation the file picks, so it is
+	// applied by condition rather than in the binding properties
+	vimOnly := func(r *Router, b Binding) map[string]any {
+		if r.notation != NotationVim {
+			return nil
+		}
+		return vimPattern
+	}
+	anyVim := func(r *Router, b Binding) map[string]any { return vimPattern }
+	checks := func(leaf func(*Router, Binding) map[string]any) map[string]any {
+		app, global := schemaSections(routers, false, leaf)
+		return map[string]any{"properties": map[string]any{
+			appSection(appName): nestApp(appName, app),
+			"global":            global,
+		}}
+	}
+
+	schema := map[string]any{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "riffkey configuration",
+		"type":        "object",
+		"properties":  properties,
+		"definitions": map[string]any{"vimPattern": map[string]any{"type": "string", "pattern": vimPatternRegex()}},
+		"allOf": []any{
+			map[string]any{
+				"if":   map[string]any{"required": []string{"notation"}, "properties": map[string]any{"notation": map[string]any{"const": "vim"}}},
+				"then": checks(anyVim),
+			},
+			map[string]any{
+				"if":   map[string]any{"not": map[string]any{"required": []string{"notation"}}},
+				"then": checks(vimOnly),
+			},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(schema)
+}
+
+// vimPattern accepts a vim-notation pattern, or false to unbind.
+var vimPattern = map[string]any{"anyOf": []any{
+	map[string]any{"$ref": "#/definitions/vimPattern"},
+	map[string]any{"enum": []any{false}},
+}}
+
+// schemaSections describes the app's section and [global] for routers, the
+// main router first, with leaf giving each binding's schema. Unless strict,
+// the app's section allows unknown actions too.
+func schemaSections(routers []*Router, strict bool, leaf func(*Router, Binding) map[string]any) (app, global map[string]any) {
+	app = schemaObject(routers[0], !strict, leaf)
+	global = schemaObject(routers[0], true, leaf)
+	for _, m := range routers {
+		if m.name == "" {
+			continue
+		}
+		app["properties"].(map[string]any)[m.name] = schemaObject(m, !strict, leaf)
+		global["properties"].(map[string]any)[m.name] = schemaObject(m, true, leaf)
+	}
+	return app, global
+}
+
+// appSection returns the top-level table of a dotted app name.
+func appSection(appName string) string {
+	name, _, _ := strings.Cut(appName, ".")
+	return name
+}
+
+// nestApp wraps section in the tables of a dotted app name: [browse.toc] is
+// browse -> toc. It returns the outermost table, under appSection(appName).
+func nestApp(appName string, section map[string]any) map[string]any {
+	parts := strings.Split(appName, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		section = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{parts[i]: section},
+		}
+	}
+	return section
+}
+
+// schemaObject describes a router's named bindings as a table, with leaf
+// giving each binding's schema; bindings it returns nil for are left out.
+// Namespaced bindings of mounted sub-routers become nested tables. A
+// [global] table allows other apps' actions as well.
+func schemaObject(r *Router, open bool, leaf func(*Router, Binding) map[string]any) map[string]any {
+	root := map[string]any{"type": "object", "properties": map[string]any{}}
+	if !open {
+		root["additionalProperties"] = false
+	}
+	for _, b := range r.Bindings() {
+		binding := leaf(r, b)
+		if binding == nil {
+			continue
+		}
+		table := root
+		parts := strings.Split(b.Name, ".")
+		for _, part := range parts[:len(parts)-1] {
+			props := table["properties"].(map[string]any)
+			next, ok := props[part].(map[string]any)
+			if !ok {
+				next = map[string]any{"type": "object", "properties": map[string]any{}}
+				if !open {
+					next["additionalProperties"] = false
+				}
+				props[part] = next
+			}
+			table = next
+		}
+		table["properties"].(map[string]any)[parts[len(parts)-1]] = binding
+	}
+	return root
+}
+
+func schemaBinding(r *Router, b Binding) map[string]any {
+	description := b.Description
+	if description != "" {
+		description += "\n\n"
+	}
+	description += "Default: " + b.DefaultPattern + `. Set to "" or false to unbind.`
+	return map[string]any{
+		"description": description,
+		"default":     b.DefaultPattern,
+		"anyOf":       []any{map[string]any{"type": "string"}, map[string]any{"enum": []any{false}}},
+	}
+}
+
+// vimPatternRegex returns a regex matching vim-notation patterns made of
+// plain characters and <...> keys. Modified keys must use a special key
+// name, a literal-character name or a single character; an unmodified
+// <Name> may also be an alias, which the config file can define itself, so
+// any name is accepted there. Names match case-insensitively, as
+// ParsePattern does. As in ParsePattern, a '<' that doesn't start a key is
+// literal, so "<<", "<>" and "a<b" match; only a <...> that looks like a
+// key name (see Notation.check) must be a valid one.
+func vimPatternRegex() string {
+	names := slices.Collect(maps.Keys(vimToSpecial))
+	names = append(names, slices.Collect(maps.Keys(vimToRune))...)
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+
+	alternatives := make([]string, len(names))
+	for i, name := range names {
+		var sb strings.Builder
+		for _, c := range name {
+			lower, upper := unicode.ToLower(c), unicode.ToUpper(c)
+			if lower == upper {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+			} else {
+				sb.WriteString("[" + string(upper) + string(lower) + "]")
+			}
+		}
+		alternatives[i] = sb.String()
+	}
+
+	const mod = `(?:[CcAaMmSsDd]-)`
+	key := `<` + mod + `*(?:` + strings.Join(alternatives, "|") + `)>` +
+		`|<` + mod + `+[^>]>` +
+		`|<[A-Za-z][A-Za-z0-9_]*>`
+	// a literal '<': followed by nothing, by a character that can't start a
+	// name, or by a name that doesn't end in '>'. One followed by another
+	// '<' is literal whatever comes between.
+	const word = `[A-Za-z][A-Za-z0-9-]*`
+	literal := `<(?:[^A-Za-z<]|` + word + `(?:[^A-Za-z0-9<>-]|$)|$)`
+	return `^(?:(?:<(?:` + word + `)?)*(?:` + key + `|` + literal + `)|[^<])*$`
+}
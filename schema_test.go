@@ -0,0 +1,163 @@
+package riffkey
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestVimPatternRegex(t *testing.T) {
+	re := regexp.MustCompile(vimPatternRegex())
+	tests := []struct {
+		pattern string
+		ok      bool
+	}{
+		{"", true},
+		{"gg", true},
+		{"<C-w>j", true},
+		{"<c-W><ESC>", true},
+		{"<S-F12>", true},
+		{"<D-s>", true},
+		{"<lt>a<Bar>", true},
+		{"<Leader>f", true},
+		{"<C-Leader>", false},
+		{"<C-Hoem>", false},
+		{"a<b", true},
+		{"<<", true},
+		{"<>", true},
+		{">>", true},
+		{"<C-", true},
+		{"<ab<Esc>", true},
+		{"<<Leader>", true},
+		{"<C-Hoem>x", false},
+		{"<<C-Hoem>", false},
+	}
+	for _, tt := range tests {
+		if got := re.MatchString(tt.pattern); got != tt.ok {
+			t.Errorf("match %q = %v, want %v", tt.pattern, got, tt.ok)
+		}
+	}
+}
+
+func TestWriteSchema(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.Describe("quit", "Quit the app")
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	r.Mount("<Space>g", git)
+	normal := NewRouter().Name("normal")
+	normal.HandleNamed("down", "j", func(m Match) {})
+
+	var buf strings.Builder
+	if err := r.WriteSchema(&buf, "myapp", normal); err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &schema); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	get := func(path ...string) map[string]any {
+		t.Helper()
+		node := schema
+		for _, p := range path {
+			next, ok := node["properties"].(map[string]any)[p].(map[string]any)
+			if !ok {
+				t.Fatalf("no property %q in %v", p, path)
+			}
+			node = next
+		}
+		return node
+	}
+
+	quit := get("myapp", "quit")
+	if quit["default"] != "q" || !strings.HasPrefix(quit["description"].(string), "Quit the app") {
+		t.Errorf("quit = %v", quit)
+	}
+	if get("myapp")["additionalProperties"] != false {
+		t.Error("app section should reject unknown actions")
+	}
+	if _, ok := get("global")["additionalProperties"]; ok {
+		t.Error("[global] should allow other apps' actions")
+	}
+	if get("myapp", "git", "status")["default"] != "<Space>gs" {
+		t.Error("mounted bindings should be nested tables")
+	}
+	if get("myapp", "normal", "down")["default"] != "j" {
+		t.Error("modes should have their own table")
+	}
+	get("global", "normal", "down")
+	get("aliases")
+	get("include")
+}
+
+func TestWriteSchemaFileNotation(t *testing.T) {
+	r := NewRouter(WithNotation(NotationEmacs))
+	r.HandleNamed("save", "C-x C-s", func(m Match) {})
+	normal := NewRouter().Name("normal")
+	normal.HandleNamed("down", "j", func(m Match) {})
+
+	var buf strings.Builder
+	if err := r.WriteSchema(&buf, "myapp", normal); err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &schema); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	down := schema["properties"].(map[string]any)["myapp"].(map[string]any)["properties"].(map[string]any)["normal"].(map[string]any)["properties"].(map[string]any)["down"]
+	if strings.Contains(fmt.Sprint(down), "vimPattern") {
+		t.Error("binding properties shouldn't pick a regex before the file's notation is known")
+	}
+
+	// checked reports whether a branch of allOf applies the vim regex to
+	// the binding at path
+	checked := func(branch int, path ...string) bool {
+		t.Helper()
+		node := schema["allOf"].([]any)[branch].(map[string]any)["then"].(map[string]any)
+		for _, p := range path {
+			next, ok := node["properties"].(map[string]any)[p].(map[string]any)
+			if !ok {
+				return false
+			}
+			node = next
+		}
+		return strings.Contains(fmt.Sprint(node), "#/definitions/vimPattern")
+	}
+	// notation = "vim": every pattern is vim
+	if !checked(0, "myapp", "save") || !checked(0, "myapp", "normal", "down") || !checked(0, "global", "save") {
+		t.Error(`notation = "vim" should check every pattern against the regex`)
+	}
+	// no notation: each router's own
+	if checked(1, "myapp", "save") || checked(1, "global", "save") {
+		t.Error("an emacs router's patterns shouldn't be checked as vim")
+	}
+	if !checked(1, "myapp", "normal", "down") || !checked(1, "global", "normal", "down") {
+		t.Error("a vim router's patterns should be checked when the file sets no notation")
+	}
+	if _, ok := schema["allOf"].([]any)[1].(map[string]any)["then"].(map[string]any)["properties"].(map[string]any)["myapp"].(map[string]any)["additionalProperties"]; ok {
+		t.Error("the checks shouldn't reject the bindings they leave out")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	r.Mount("<Space>g", git)
+
+	if !r.Describe("quit", "Quit") || !r.Describe("git.status", "Show status") {
+		t.Fatal("Describe should find own and mounted bindings")
+	}
+	if r.Describe("nope", "x") {
+		t.Error("Describe of an unknown binding should fail")
+	}
+	got := r.Bindings()
+	if got[0].Description != "Quit" || got[1].Description != "Show status" {
+		t.Errorf("descriptions = %q, %q", got[0].Description, got[1].Description)
+	}
+}
@@ -0,0 +1,208 @@
+package riffkey
+
+import (
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WatchOptions configures Router.WatchBindings.
+type WatchOptions struct {
+	// Paths are the config files to load and watch, lowest precedence
+	// first, as for LoadBindingsFromPaths. Files they include are watched
+	// too. Defaults to ConfigPaths().
+	Paths []string
+
+	// Modes are the app's named mode routers, as for LoadBindings. They are
+	// reset and reloaded along with the watched router.
+	Modes []*Router
+
+	// Interval is how often the file is polled for changes. Defaults to 1s.
+	Interval time.Duration
+
+	// Input, if set, is locked while new bindings are swapped in, so a
+	// reload is atomic with respect to its Dispatch. Any partially typed
+	// sequence is discarded.
+	Input *Input
+
+	// OnError receives read and parse errors. The previous bindings stay in
+	// place until the file parses again.
+	OnError func(error)
+
+	// OnReload is called after new bindings have been applied, with the
+	// diagnostics of the load.
+	OnReload func([]Diagnostic)
+}
+
+// ConfigWatcher reloads a router's bindings when its config files change.
+type ConfigWatcher struct {
+	appName string
+	opts    WatchOptions
+	routers []*Router           // the watched router followed by the modes
+	aliases []map[string]string // aliases set by the app per router, restored before each reload
+
+	mu      sync.Mutex
+	watched []string
+	state   map[string]fileState
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+// WatchBindings loads bindings for appName like LoadBindings and keeps them
+// in sync with the config files. On every change the files are re-parsed, the
+// router is reset to its defaults (the bindings and aliases registered by the
+// app) and the aliases, [global] and app sections are re-applied. The new
+// bindings, those of mounted sub-routers included, are prepared on forks and
+// swapped in at once, so handlers never see a half-applied config.
+//
+// Call Stop to end watching.
+//
+// Example:
+//
+//	w := router.WatchBindings("myapp", riffkey.WatchOptions{
+//	    Input:   input,
+//	    OnError: func(err error) { status = "riffkey.toml: " + err.Error() },
+//	})
+//	defer w.Stop()
+func (r *Router) WatchBindings(appName string, opts WatchOptions) *ConfigWatcher {
+	if opts.Paths == nil {
+		opts.Paths = ConfigPaths()
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	w := &ConfigWatcher{
+		appName: appName,
+		opts:    opts,
+		routers: append([]*Router{r}, opts.Modes...),
+		watched: opts.Paths,
+		state:   make(map[string]fileState),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, m := range w.routers {
+		m.mu.Lock()
+		w.aliases = append(w.aliases, maps.Clone(m.aliases))
+		m.mu.Unlock()
+	}
+	w.changed() // record the initial file states
+	w.reload()
+	go w.run()
+	return w
+}
+
+// Stop ends watching. Bindings stay as last applied.
+func (w *ConfigWatcher) Stop() {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+}
+
+// Reload re-applies the config files immediately, whether or not they
+// changed.
+func (w *ConfigWatcher) Reload() error {
+	w.changed()
+	return w.reload()
+}
+
+func (w *ConfigWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if w.changed() {
+				w.reload()
+			}
+		}
+	}
+}
+
+// changed stats the watched files and reports whether any differs from its
+// last recorded state.
+func (w *ConfigWatcher) changed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changed := false
+	for _, path := range w.watched {
+		var st fileState
+		if info, err := os.Stat(path); err == nil {
+			st = fileState{exists: true, modTime: info.ModTime(), size: info.Size()}
+		}
+		if prev, ok := w.state[path]; !ok || prev.exists != st.exists || !prev.modTime.Equal(st.modTime) || prev.size != st.size {
+			w.state[path] = st
+			changed = true
+		}
+	}
+	return changed
+}
+
+// reload parses the config onto forks of the routers, and of the routers
+// mounted in them, reset to their defaults, then swaps the forks' bindings
+// in.
+func (w *ConfigWatcher) reload() error {
+	files, err := readConfigs(w.opts.Paths)
+	if err != nil {
+		if w.opts.OnError != nil {
+			w.opts.OnError(err)
+		}
+		return err
+	}
+
+	// watch included files too
+	w.mu.Lock()
+	for _, f := range files {
+		if !slices.Contains(w.watched, f.path) {
+			w.watched = append(w.watched, f.path)
+		}
+	}
+	w.mu.Unlock()
+	w.changed()
+
+	forks := make([]*Router, len(w.routers))
+	seen := make(map[*Router]*Router)
+	for i, m := range w.routers {
+		forks[i] = m.fork(seen)
+		forks[i].aliases = maps.Clone(w.aliases[i])
+		forks[i].ResetAll()
+		forks[i].rebuild()
+	}
+	diags := forks[0].applyConfigs(files, w.appName, forks[1:])
+
+	w.swap(forks)
+	if w.opts.OnReload != nil {
+		w.opts.OnReload(diags)
+	}
+	return nil
+}
+
+// swap replaces the bindings of the routers and their mounted sub-routers
+// with the forks', holding the Input lock if one was given. Tables are
+// copied in place so clones sharing them (OnAfter, OnBefore) see the new
+// bindings too.
+func (w *ConfigWatcher) swap(forks []*Router) {
+	if in := w.opts.Input; in != nil {
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		in.clearBuffer()
+	}
+	done := make(map[*Router]bool)
+	for i, m := range w.routers {
+		m.adopt(forks[i], done)
+	}
+}
@@ -0,0 +1,157 @@
+package riffkey
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchBindingsReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp]\nquit = \"Q\"\n")
+
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+	r.HandleNamed("save", "s", func(m Match) {})
+
+	reloaded := make(chan struct{}, 4)
+	w := r.WatchBindings("myapp", WatchOptions{
+		Paths:    []string{path},
+		Interval: 5 * time.Millisecond,
+		Input:    NewInput(r),
+		OnReload: func([]Diagnostic) { reloaded <- struct{}{} },
+	})
+	defer w.Stop()
+	<-reloaded
+
+	if got := r.BindingsMap()["quit"]; got != "Q" {
+		t.Fatalf("initial load: quit = %q, want Q", got)
+	}
+
+	// Dropping the quit entry resets it to its default.
+	writeConfig(t, path, "[myapp]\nsave = \"<C-s>\"\n")
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("change was not picked up")
+	}
+	if got := r.BindingsMap()["quit"]; got != "q" {
+		t.Errorf("quit = %q, want default q", got)
+	}
+	if got := r.BindingsMap()["save"]; got != "<C-s>" {
+		t.Errorf("save = %q, want <C-s>", got)
+	}
+}
+
+func TestWatchBindingsKeepsOldOnParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp]\nquit = \"Q\"\n")
+
+	r := NewRouter()
+	r.HandleNamed("quit", "q", func(m Match) {})
+
+	var errs []error
+	w := r.WatchBindings("myapp", WatchOptions{
+		Paths:    []string{path},
+		Interval: time.Hour,
+		OnError:  func(err error) { errs = append(errs, err) },
+	})
+	defer w.Stop()
+
+	writeConfig(t, path, "[myapp\nquit = \"x\"\n")
+	if err := w.Reload(); err == nil {
+		t.Fatal("Reload should report the parse error")
+	}
+	if len(errs) != 1 {
+		t.Errorf("OnError called %d times, want 1", len(errs))
+	}
+	if got := r.BindingsMap()["quit"]; got != "Q" {
+		t.Errorf("quit = %q, old binding Q should stay in place", got)
+	}
+}
+
+func TestWatchBindingsRestoresAppAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[aliases]\nLeader = \",\"\n")
+
+	r := NewRouter().SetAlias("Leader", "\\")
+	hits := 0
+	r.HandleNamed("find", "<Leader>f", func(m Match) { hits++ })
+	in := NewInput(r)
+
+	w := r.WatchBindings("myapp", WatchOptions{Paths: []string{path}, Interval: time.Hour, Input: in})
+	defer w.Stop()
+
+	in.Dispatch(Key{Rune: ','})
+	in.Dispatch(Key{Rune: 'f'})
+	if hits != 1 {
+		t.Fatal("config Leader should apply")
+	}
+
+	writeConfig(t, path, "")
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	in.Dispatch(Key{Rune: '\\'})
+	in.Dispatch(Key{Rune: 'f'})
+	if hits != 2 {
+		t.Error("removing the config alias should restore the app's Leader")
+	}
+}
+
+func TestWatchBindingsSwapsMountedRouters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "riffkey.toml")
+	writeConfig(t, path, "[myapp]\ngit.status = \"gx\"\n")
+
+	git := NewRouter().Name("git")
+	git.HandleNamed("status", "s", func(m Match) {})
+	r := NewRouter()
+	r.Mount("g", git)
+	in := NewInput(r)
+
+	w := r.WatchBindings("myapp", WatchOptions{Paths: []string{path}, Interval: time.Hour, Input: in})
+	defer w.Stop()
+	if got := git.BindingsMap()["status"]; got != "x" {
+		t.Fatalf("status = %q, want x", got)
+	}
+
+	// With dispatch held off, the reload must not touch the mounted router
+	// until it swaps everything in at once.
+	writeConfig(t, path, "[myapp]\ngit.status = \"gy\"\n")
+	in.mu.Lock()
+	reloaded := make(chan error)
+	go func() { reloaded <- w.Reload() }()
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if got := git.BindingsMap()["status"]; got != "x" {
+			in.mu.Unlock()
+			t.Fatalf("status changed to %q before the swap", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	in.mu.Unlock()
+	if err := <-reloaded; err != nil {
+		t.Fatal(err)
+	}
+	if got := git.BindingsMap()["status"]; got != "y" {
+		t.Errorf("status = %q after reload, want y", got)
+	}
+
+	// the original sub-router is still the one mounted
+	hits := 0
+	git.Handle("l", func(m Match) { hits++ })
+	in.Dispatch(Key{Rune: 'g'})
+	in.Dispatch(Key{Rune: 'l'})
+	if hits != 1 {
+		t.Error("later bindings on the mounted router should show through")
+	}
+}